@@ -3,8 +3,11 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"sort"
+	"time"
 
 	"github.com/techarm/jclog/internal/config"
+	"github.com/techarm/jclog/internal/logparser"
 	"github.com/urfave/cli/v3"
 )
 
@@ -41,11 +44,45 @@ func NewConfigCommand() *cli.Command {
 					for name, profile := range cfg.Profiles {
 						fmt.Printf("\n[%s]\n", name)
 						fmt.Printf("  Format: %s\n", profile.Format)
+						if profile.TemplateEngine != "" {
+							fmt.Printf("  TemplateEngine: %s\n", profile.TemplateEngine)
+						}
 						fmt.Printf("  Fields: %v\n", profile.Fields)
 						fmt.Printf("  MaxDepth: %d\n", profile.MaxDepth)
 						fmt.Printf("  HideMissing: %v\n", profile.HideMissing)
+						if profile.RawLevel {
+							fmt.Printf("  RawLevel: %v\n", profile.RawLevel)
+						}
 						fmt.Printf("  Filters: %v\n", profile.Filters)
 						fmt.Printf("  Excludes: %v\n", profile.Excludes)
+						if profile.Where != "" {
+							fmt.Printf("  Where: %s\n", profile.Where)
+						}
+						if profile.MinLevel != "" || profile.MaxLevel != "" {
+							fmt.Printf("  Levels: %s..%s\n", profile.MinLevel, profile.MaxLevel)
+						}
+						if profile.EpochPrecision != "" {
+							fmt.Printf("  EpochPrecision: %s\n", profile.EpochPrecision)
+						}
+						if len(profile.TimeInputLayouts) > 0 {
+							fmt.Printf("  TimeInputLayouts: %q\n", profile.TimeInputLayouts)
+						}
+						if profile.TimeField != "" {
+							fmt.Printf("  TimeField: %s\n", profile.TimeField)
+						}
+						if profile.Timezone != "" {
+							fmt.Printf("  Timezone: %s\n", profile.Timezone)
+						}
+						if profile.InputTimezone != "" {
+							fmt.Printf("  InputTimezone: %s\n", profile.InputTimezone)
+						}
+						if profile.GapThreshold != "" {
+							fmt.Printf("  GapThreshold: %s\n", profile.GapThreshold)
+						}
+					}
+					if len(cfg.Queries) > 0 {
+						fmt.Println("\nSaved queries:")
+						printQueries(cfg.Queries)
 					}
 					return nil
 				},
@@ -53,6 +90,8 @@ func NewConfigCommand() *cli.Command {
 			{
 				Name:  "add-profile",
 				Usage: "Add a new profile",
+				// Subcommands reset the separator setting; keep commas in filters and layouts
+				DisableSliceFlagSeparator: true,
 				Flags: []cli.Flag{
 					&cli.StringFlag{
 						Name:     "name",
@@ -63,6 +102,10 @@ func NewConfigCommand() *cli.Command {
 						Name:  "format",
 						Usage: "Output format",
 					},
+					&cli.StringFlag{
+						Name:  "template-engine",
+						Usage: "Syntax of the format: jclog ({field} placeholders, default) or go (text/template)",
+					},
 					&cli.StringSliceFlag{
 						Name:  "fields",
 						Usage: "Fields to display",
@@ -76,6 +119,10 @@ func NewConfigCommand() *cli.Command {
 						Name:  "hide-missing",
 						Usage: "Hide missing fields",
 					},
+					&cli.BoolFlag{
+						Name:  "raw-level",
+						Usage: "Show levels as written instead of by their normalized names",
+					},
 					&cli.StringSliceFlag{
 						Name:  "filter",
 						Usage: "Filter conditions",
@@ -84,6 +131,42 @@ func NewConfigCommand() *cli.Command {
 						Name:  "exclude",
 						Usage: "Exclude conditions",
 					},
+					&cli.StringFlag{
+						Name:  "where",
+						Usage: "Boolean filter expression",
+					},
+					&cli.StringFlag{
+						Name:  "min-level",
+						Usage: "Minimum log level to show",
+					},
+					&cli.StringFlag{
+						Name:  "max-level",
+						Usage: "Maximum log level to show",
+					},
+					&cli.StringFlag{
+						Name:  "epoch-precision",
+						Usage: "Unit of numeric timestamps: auto (detect by magnitude), s, ms, us or ns",
+					},
+					&cli.StringFlag{
+						Name:  "timezone",
+						Usage: "Timezone to show timestamps in (e.g., Asia/Tokyo; default: local time)",
+					},
+					&cli.StringFlag{
+						Name:  "input-timezone",
+						Usage: "Timezone of timestamps written without an offset (default: UTC)",
+					},
+					&cli.StringSliceFlag{
+						Name:  "time-input-layout",
+						Usage: "Go time layout of the log's timestamps, tried before the built-in ones (repeatable, e.g., \"02/Jan/2006:15:04:05 -0700\")",
+					},
+					&cli.StringFlag{
+						Name:  "time-field",
+						Usage: "Field holding the timestamp, when it is not timestamp, time or ts",
+					},
+					&cli.StringFlag{
+						Name:  "gap-threshold",
+						Usage: "Highlight {timestamp|since} and {timestamp|delta} when the gap from the previous log is longer (default: 1s, 0 disables)",
+					},
 				},
 				Action: func(ctx context.Context, cmd *cli.Command) error {
 					configPath := config.GetDefaultConfigPath()
@@ -93,14 +176,80 @@ func NewConfigCommand() *cli.Command {
 					}
 
 					name := cmd.String("name")
+					switch engine := cmd.String("template-engine"); engine {
+					case "", "jclog":
+						if err := logparser.ValidateFormat(cmd.String("format")); err != nil {
+							return err
+						}
+					case "go":
+						if _, err := logparser.ParseGoTemplate(cmd.String("format"), logparser.TimeSettings{}, nil); err != nil {
+							return err
+						}
+					default:
+						return fmt.Errorf("unknown template engine %q: expected jclog or go", engine)
+					}
+					filters := cmd.StringSlice("filter")
+					if _, err := parseFilterArgs(filters); err != nil {
+						return err
+					}
+					excludes := cmd.StringSlice("exclude")
+					if _, err := parseFilterArgs(excludes); err != nil {
+						return err
+					}
+					if where := cmd.String("where"); where != "" {
+						if _, err := logparser.ParseWhere(where); err != nil {
+							return err
+						}
+					}
+
+					for _, level := range []string{cmd.String("min-level"), cmd.String("max-level")} {
+						if level == "" {
+							continue
+						}
+						if _, err := logparser.ParseLevel(level); err != nil {
+							return err
+						}
+					}
+
+					if _, err := logparser.ParseEpochUnit(cmd.String("epoch-precision")); err != nil {
+						return err
+					}
+					for _, layout := range cmd.StringSlice("time-input-layout") {
+						if err := logparser.ValidateTimeLayout(layout); err != nil {
+							return err
+						}
+					}
+					if _, err := parseGapThreshold(cmd.String("gap-threshold"), ""); err != nil {
+						return err
+					}
+					for _, tz := range []string{cmd.String("timezone"), cmd.String("input-timezone")} {
+						if tz == "" {
+							continue
+						}
+						if _, err := logparser.LoadTimezone(tz); err != nil {
+							return err
+						}
+					}
+
 					maxDepth := int(cmd.Int("max-depth"))
 					profile := config.Profile{
-						Format:      cmd.String("format"),
-						Fields:      cmd.StringSlice("fields"),
-						MaxDepth:    maxDepth,
-						HideMissing: cmd.Bool("hide-missing"),
-						Filters:     cmd.StringSlice("filter"),
-						Excludes:    cmd.StringSlice("exclude"),
+						Format:           cmd.String("format"),
+						TemplateEngine:   cmd.String("template-engine"),
+						Fields:           splitFieldArgs(cmd.StringSlice("fields")),
+						MaxDepth:         maxDepth,
+						HideMissing:      cmd.Bool("hide-missing"),
+						RawLevel:         cmd.Bool("raw-level"),
+						Filters:          filters,
+						Excludes:         excludes,
+						Where:            cmd.String("where"),
+						MinLevel:         cmd.String("min-level"),
+						MaxLevel:         cmd.String("max-level"),
+						EpochPrecision:   cmd.String("epoch-precision"),
+						Timezone:         cmd.String("timezone"),
+						InputTimezone:    cmd.String("input-timezone"),
+						TimeInputLayouts: cmd.StringSlice("time-input-layout"),
+						TimeField:        cmd.String("time-field"),
+						GapThreshold:     cmd.String("gap-threshold"),
 					}
 
 					cfg.Profiles[name] = profile
@@ -178,6 +327,168 @@ func NewConfigCommand() *cli.Command {
 					return nil
 				},
 			},
+			{
+				Name:  "add-query",
+				Usage: "Add a saved query",
+				// Subcommands reset the separator setting; keep commas in filters
+				DisableSliceFlagSeparator: true,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "name",
+						Usage:    "Query name",
+						Required: true,
+					},
+					&cli.StringSliceFlag{
+						Name:  "filter",
+						Usage: "Filter conditions",
+					},
+					&cli.StringSliceFlag{
+						Name:  "exclude",
+						Usage: "Exclude conditions",
+					},
+					&cli.StringFlag{
+						Name:  "where",
+						Usage: "Boolean filter expression",
+					},
+					&cli.StringFlag{
+						Name:  "since",
+						Usage: "Start of the time window (e.g., 15m, today)",
+					},
+					&cli.StringFlag{
+						Name:  "until",
+						Usage: "End of the time window",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					configPath := config.GetDefaultConfigPath()
+					cfg, err := config.LoadConfig(configPath)
+					if err != nil {
+						return fmt.Errorf("failed to load config: %v", err)
+					}
+
+					name := cmd.String("name")
+					query := config.Query{
+						Filters:  cmd.StringSlice("filter"),
+						Excludes: cmd.StringSlice("exclude"),
+						Where:    cmd.String("where"),
+						Since:    cmd.String("since"),
+						Until:    cmd.String("until"),
+					}
+					if err := validateQuery(query); err != nil {
+						return err
+					}
+
+					cfg.Queries[name] = query
+					if err := config.SaveConfig(cfg, configPath); err != nil {
+						return fmt.Errorf("failed to save config: %v", err)
+					}
+
+					fmt.Printf("Added query '%s'\n", name)
+					return nil
+				},
+			},
+			{
+				Name:  "list-queries",
+				Usage: "List saved queries",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					configPath := config.GetDefaultConfigPath()
+					cfg, err := config.LoadConfig(configPath)
+					if err != nil {
+						return fmt.Errorf("failed to load config: %v", err)
+					}
+
+					if len(cfg.Queries) == 0 {
+						fmt.Println("No saved queries")
+						return nil
+					}
+					printQueries(cfg.Queries)
+					return nil
+				},
+			},
+			{
+				Name:  "remove-query",
+				Usage: "Remove a saved query",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "name",
+						Usage:    "Query name",
+						Required: true,
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					configPath := config.GetDefaultConfigPath()
+					cfg, err := config.LoadConfig(configPath)
+					if err != nil {
+						return fmt.Errorf("failed to load config: %v", err)
+					}
+
+					name := cmd.String("name")
+					if _, exists := cfg.Queries[name]; !exists {
+						return fmt.Errorf("query '%s' does not exist", name)
+					}
+
+					delete(cfg.Queries, name)
+					if err := config.SaveConfig(cfg, configPath); err != nil {
+						return fmt.Errorf("failed to save config: %v", err)
+					}
+
+					fmt.Printf("Removed query '%s'\n", name)
+					return nil
+				},
+			},
 		},
 	}
 }
+
+// validateQuery checks that every condition in a saved query can be parsed
+func validateQuery(query config.Query) error {
+	if _, err := parseFilterArgs(query.Filters); err != nil {
+		return err
+	}
+	if _, err := parseFilterArgs(query.Excludes); err != nil {
+		return err
+	}
+	if query.Where != "" {
+		if _, err := logparser.ParseWhere(query.Where); err != nil {
+			return err
+		}
+	}
+	for _, bound := range []string{query.Since, query.Until} {
+		if bound == "" {
+			continue
+		}
+		if _, err := logparser.ParseTimeBound(bound, time.Now()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printQueries prints saved queries sorted by name
+func printQueries(queries map[string]config.Query) {
+	names := make([]string, 0, len(queries))
+	for name := range queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		query := queries[name]
+		fmt.Printf("\n[%s]\n", name)
+		if len(query.Filters) > 0 {
+			fmt.Printf("  Filters: %v\n", query.Filters)
+		}
+		if len(query.Excludes) > 0 {
+			fmt.Printf("  Excludes: %v\n", query.Excludes)
+		}
+		if query.Where != "" {
+			fmt.Printf("  Where: %s\n", query.Where)
+		}
+		if query.Since != "" {
+			fmt.Printf("  Since: %s\n", query.Since)
+		}
+		if query.Until != "" {
+			fmt.Printf("  Until: %s\n", query.Until)
+		}
+	}
+}
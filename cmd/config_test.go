@@ -5,6 +5,9 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/techarm/jclog/internal/config"
 )
 
 func TestConfigCommands(t *testing.T) {
@@ -48,10 +51,31 @@ func TestConfigCommands(t *testing.T) {
 			"--fields", "timestamp,message",
 			"--max-depth", "1",
 			"--hide-missing",
-			"--filter", "level=INFO",
+			"--filter", "level=ERROR,WARN",
+			"--filter", "msg=~^retry \\d{1,3}",
+			"--where", `http_code >= 500 || level == "ERROR"`,
+			"--min-level", "INFO",
+			"--time-input-layout", "02/Jan/2006:15:04:05 -0700",
+			"--time-input-layout", time.RFC1123,
+			"--time-field", "created_at",
 		}
 		if err := rootCmd.Run(ctx, args); err != nil {
-			t.Errorf("Add profile failed: %v", err)
+			t.Fatalf("Add profile failed: %v", err)
+		}
+
+		cfg, err := config.LoadConfig(filepath.Join(tmpDir, ".jclog.json"))
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		profile := cfg.Profiles["test"]
+		if len(profile.Filters) != 2 || profile.Filters[0] != "level=ERROR,WARN" || profile.Filters[1] != `msg=~^retry \d{1,3}` {
+			t.Errorf("Unexpected saved filters: %q", profile.Filters)
+		}
+		if len(profile.Fields) != 2 {
+			t.Errorf("Unexpected saved fields: %q", profile.Fields)
+		}
+		if len(profile.TimeInputLayouts) != 2 || profile.TimeInputLayouts[0] != "02/Jan/2006:15:04:05 -0700" || profile.TimeInputLayouts[1] != time.RFC1123 || profile.TimeField != "created_at" {
+			t.Errorf("Unexpected saved time settings: %q, %q", profile.TimeInputLayouts, profile.TimeField)
 		}
 	})
 
@@ -79,6 +103,49 @@ func TestConfigCommands(t *testing.T) {
 		}
 	})
 
+	// Test saved queries
+	t.Run("Queries", func(t *testing.T) {
+		args := []string{"jclog", "config", "add-query",
+			"--name", "payment-5xx",
+			"--filter", "service=payment",
+			"--filter", "level=ERROR,WARN",
+			"--where", "http_code >= 500",
+			"--since", "1h",
+		}
+		if err := rootCmd.Run(ctx, args); err != nil {
+			t.Fatalf("Add query failed: %v", err)
+		}
+
+		cfg, err := config.LoadConfig(filepath.Join(tmpDir, ".jclog.json"))
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		query, exists := cfg.Queries["payment-5xx"]
+		if !exists {
+			t.Fatal("Expected query to be saved")
+		}
+		if query.Where != "http_code >= 500" || query.Since != "1h" || len(query.Filters) != 2 || query.Filters[1] != "level=ERROR,WARN" {
+			t.Errorf("Unexpected saved query: %+v", query)
+		}
+
+		if err := rootCmd.Run(ctx, []string{"jclog", "config", "list-queries"}); err != nil {
+			t.Errorf("List queries failed: %v", err)
+		}
+
+		if err := rootCmd.Run(ctx, []string{"jclog", "config", "remove-query", "--name", "payment-5xx"}); err != nil {
+			t.Errorf("Remove query failed: %v", err)
+		}
+		if err := rootCmd.Run(ctx, []string{"jclog", "config", "remove-query", "--name", "payment-5xx"}); err == nil {
+			t.Error("Expected error when removing nonexistent query")
+		}
+
+		// Invalid conditions are rejected
+		args = []string{"jclog", "config", "add-query", "--name", "broken", "--since", "whenever"}
+		if err := rootCmd.Run(ctx, args); err == nil {
+			t.Error("Expected error when adding query with invalid time window")
+		}
+	})
+
 	// Test error cases
 	t.Run("Error Cases", func(t *testing.T) {
 		// Try to remove default profile
@@ -87,6 +154,34 @@ func TestConfigCommands(t *testing.T) {
 			t.Error("Expected error when removing default profile")
 		}
 
+		// Try to add a profile with an invalid where expression
+		args = []string{"jclog", "config", "add-profile", "--name", "broken", "--where", "(level =="}
+		if err := rootCmd.Run(ctx, args); err == nil {
+			t.Error("Expected error when adding profile with invalid where expression")
+		}
+
+		// Try to add a profile with an invalid filter or exclude
+		args = []string{"jclog", "config", "add-profile", "--name", "broken", "--filter", "msg=~("}
+		if err := rootCmd.Run(ctx, args); err == nil {
+			t.Error("Expected error when adding profile with invalid filter")
+		}
+		args = []string{"jclog", "config", "add-profile", "--name", "broken", "--exclude", "level"}
+		if err := rootCmd.Run(ctx, args); err == nil {
+			t.Error("Expected error when adding profile with invalid exclude")
+		}
+
+		// Try to add a profile with an invalid go template
+		args = []string{"jclog", "config", "add-profile", "--name", "broken", "--template-engine", "go", "--format", "{{.level"}
+		if err := rootCmd.Run(ctx, args); err == nil {
+			t.Error("Expected error when adding profile with invalid go template")
+		}
+
+		// Try to add a profile with a time layout that is not in Go's reference form
+		args = []string{"jclog", "config", "add-profile", "--name", "broken", "--time-input-layout", "yyyy-MM-dd"}
+		if err := rootCmd.Run(ctx, args); err == nil {
+			t.Error("Expected error when adding profile with invalid time layout")
+		}
+
 		// Try to set non-existent profile as active
 		args = []string{"jclog", "config", "set-active", "--name", "nonexistent"}
 		if err := rootCmd.Run(ctx, args); err == nil {
@@ -2,15 +2,14 @@ package cmd
 
 import (
 	"bufio"
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
-	"time"
 
 	"github.com/fatih/color"
 	"github.com/techarm/jclog/internal/config"
@@ -33,46 +32,8 @@ type fieldInfo struct {
 	Type    string
 	Example string
 	Order   int
-}
-
-// orderedJSON represents a JSON object with ordered fields
-type orderedJSON struct {
-	fields     map[string]any
-	fieldOrder []string
-}
-
-// UnmarshalJSON implements the json.Unmarshaler interface
-func (o *orderedJSON) UnmarshalJSON(data []byte) error {
-	// First, get the field order by decoding tokens
-	dec := json.NewDecoder(bytes.NewReader(data))
-
-	// Read the opening brace
-	if _, err := dec.Token(); err != nil {
-		return err
-	}
-
-	o.fields = make(map[string]any)
-	o.fieldOrder = make([]string, 0)
-
-	// Read field names in order
-	for dec.More() {
-		token, err := dec.Token()
-		if err != nil {
-			return err
-		}
-
-		key := token.(string)
-		o.fieldOrder = append(o.fieldOrder, key)
-
-		// Skip the value for now
-		var value any
-		if err := dec.Decode(&value); err != nil {
-			return err
-		}
-		o.fields[key] = value
-	}
-
-	return nil
+	// Container marks objects whose members are listed as nested paths
+	Container bool
 }
 
 // NewInspectCommand creates a new inspect command
@@ -98,6 +59,10 @@ func NewInspectCommand() *cli.Command {
 				return fmt.Errorf("failed to load config: %v", err)
 			}
 			activeProfile := cfg.GetActiveProfile()
+			timeSettings, err := newTimeSettings(activeProfile, "", "", "")
+			if err != nil {
+				return err
+			}
 
 			filePath := cmd.Args().Get(0)
 			file, err := os.Open(filePath)
@@ -113,31 +78,34 @@ func NewInspectCommand() *cli.Command {
 			}
 
 			// Parse JSON with field order preservation
-			var orderedData orderedJSON
+			var orderedData logparser.OrderedJSON
 			if err := json.Unmarshal([]byte(scanner.Text()), &orderedData); err != nil {
 				return fmt.Errorf("invalid JSON: %v", err)
 			}
 
-			// Get all fields with their original order
+			// Get all fields with their original order, followed by nested paths
 			fields := make(map[string]fieldInfo)
-			for i, key := range orderedData.fieldOrder {
-				value := orderedData.fields[key]
-				example := fmt.Sprintf("%v", value)
+			order := 0
+			for _, key := range orderedData.Keys {
+				value := orderedData.Fields[key]
+				example := logparser.FormatValue(value)
 
 				// Handle special cases
 				if cmd.Bool("basename") && key == "file" {
 					example = filepath.Base(example)
-				} else if key == "time" || key == "timestamp" {
-					if t, err := time.Parse(time.RFC3339Nano, example); err == nil {
-						example = t.Format(activeProfile.TimeFormat)
-					}
+				} else if slices.Contains(logparser.FieldAliases["timestamp"], key) || key == timeSettings.Field {
+					example = logparser.FormatValue(timeSettings.Display(value))
 				}
 
+				_, isObject := value.(map[string]any)
 				fields[key] = fieldInfo{
-					Type:    fmt.Sprintf("%T", value),
-					Example: example,
-					Order:   i,
+					Type:      logparser.ValueType(value),
+					Example:   example,
+					Order:     order,
+					Container: isObject,
 				}
+				order++
+				addNestedFields(fields, logparser.JoinPath("", key), value, &order)
 			}
 
 			// Print field information
@@ -153,6 +121,54 @@ func NewInspectCommand() *cli.Command {
 	}
 }
 
+// addNestedFields adds the paths of an object's members, recursively.
+// Arrays of objects are listed as name[*].child, collecting every element.
+func addNestedFields(fields map[string]fieldInfo, path string, value any, order *int) {
+	members := make(map[string]any)
+	switch v := value.(type) {
+	case map[string]any:
+		members = v
+	case []any:
+		if len(v) == 0 {
+			return
+		}
+		first, ok := v[0].(map[string]any)
+		if !ok {
+			return
+		}
+		path += "[*]"
+		for key := range first {
+			if values, ok := logparser.LookupPath(map[string]any{"items": v}, logparser.JoinPath("items[*]", key)); ok {
+				members[key] = values
+			}
+		}
+	default:
+		return
+	}
+
+	keys := make([]string, 0, len(members))
+	for key := range members {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		childPath := logparser.JoinPath(path, key)
+		child := members[key]
+		_, isObject := child.(map[string]any)
+		fields[childPath] = fieldInfo{
+			Type:      logparser.ValueType(child),
+			Example:   logparser.FormatValue(child),
+			Order:     *order,
+			Container: isObject,
+		}
+		*order++
+		if _, isArray := value.([]any); !isArray {
+			addNestedFields(fields, childPath, child, order)
+		}
+	}
+}
+
 func printFields(fields map[string]fieldInfo) {
 	// Get field names in original order
 	type fieldWithName struct {
@@ -237,7 +253,7 @@ func buildFormat(fields map[string]fieldInfo, fieldOrder []string, includeExtra
 
 	// Add ordered fields first
 	for _, field := range fieldOrder {
-		if _, exists := fields[field]; exists {
+		if info, exists := fields[field]; exists && !info.Container {
 			if field == "level" {
 				parts = append(parts, "[{level}]")
 			} else if field == "http_code" {
@@ -253,8 +269,8 @@ func buildFormat(fields map[string]fieldInfo, fieldOrder []string, includeExtra
 
 	// Add remaining fields if includeExtra is true
 	if includeExtra {
-		for field := range fields {
-			if !usedFields[field] {
+		for field, info := range fields {
+			if !usedFields[field] && !info.Container {
 				parts = append(parts, "{"+field+"}")
 				usedFields[field] = true
 			}
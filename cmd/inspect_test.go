@@ -23,11 +23,36 @@ func TestInspectCommand(t *testing.T) {
 	}
 	tmpFile.Close()
 
+	nestedLog := `{"timestamp":"2024-03-20T10:00:00Z","level":"info","http":{"request":{"method":"GET"}},"errors":[{"code":"E1"},{"code":"E2"}]}`
+	nestedFile, err := os.CreateTemp("", "test-nested-*.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(nestedFile.Name())
+
+	if _, err := nestedFile.WriteString(nestedLog); err != nil {
+		t.Fatal(err)
+	}
+	nestedFile.Close()
+
+	zapLog := `{"level":"info","ts":1647763200.123,"msg":"started"}`
+	zapFile, err := os.CreateTemp("", "test-zap-*.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(zapFile.Name())
+
+	if _, err := zapFile.WriteString(zapLog); err != nil {
+		t.Fatal(err)
+	}
+	zapFile.Close()
+
 	tests := []struct {
-		name     string
-		args     []string
-		wantErr  bool
-		contains []string
+		name        string
+		args        []string
+		wantErr     bool
+		contains    []string
+		notContains []string
 	}{
 		{
 			name:    "Inspect log file",
@@ -41,6 +66,24 @@ func TestInspectCommand(t *testing.T) {
 				"Type: string",
 			},
 		},
+		{
+			name:    "Inspect nested fields",
+			args:    []string{"jclog", "inspect", nestedFile.Name()},
+			wantErr: false,
+			contains: []string{
+				"http.request.method",
+				`Example: "GET"`,
+				"errors[*].code",
+				"{http.request.method}",
+			},
+		},
+		{
+			name:        "Inspect epoch timestamp alias",
+			args:        []string{"jclog", "inspect", zapFile.Name()},
+			wantErr:     false,
+			contains:    []string{"ts", "msg"},
+			notContains: []string{"1647763200.123"},
+		},
 		{
 			name:    "Missing file path",
 			args:    []string{"jclog", "inspect"},
@@ -92,6 +135,11 @@ func TestInspectCommand(t *testing.T) {
 						t.Errorf("Output should contain %q but got:\n%s", want, out)
 					}
 				}
+				for _, unwanted := range tt.notContains {
+					if strings.Contains(out, unwanted) {
+						t.Errorf("Output should not contain %q but got:\n%s", unwanted, out)
+					}
+				}
 			}
 		})
 	}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/techarm/jclog/internal/config"
 	"github.com/techarm/jclog/internal/logparser"
@@ -18,7 +19,7 @@ var builtinTemplates = map[string]string{
 	"detailed": "{timestamp} [{level}] {message} (service={service})",
 	"compact":  "[{level}] {message}",
 	"debug":    "{timestamp} [{level}] {message} (file={caller}:{line})",
-	"json":     "{timestamp} [{level}] {message} {data}",
+	"json":     "{timestamp} [{level}] {message} {rest|json}",
 	"metrics":  "{timestamp} {service} CPU:{cpu_usage}% MEM:{memory_usage}%",
 }
 
@@ -27,6 +28,8 @@ func NewRootCommand() *cli.Command {
 	return &cli.Command{
 		Name:  "jclog",
 		Usage: "Parse JSON log files and display them with colors",
+		// Filter values and regexes may hold commas; --fields splits its own
+		DisableSliceFlagSeparator: true,
 		Flags: []cli.Flag{
 			&cli.StringFlag{
 				Name:  "config",
@@ -38,12 +41,20 @@ func NewRootCommand() *cli.Command {
 			},
 			&cli.StringFlag{
 				Name:  "format",
-				Usage: "Specify output format (e.g., \"{timestamp} [{level}] {message}\")",
+				Usage: "Specify output format (e.g., \"{timestamp} [{level|upper}] {message|trunc:80}\")",
 			},
 			&cli.StringFlag{
 				Name:  "template",
 				Usage: "Use predefined format template",
 			},
+			&cli.StringFlag{
+				Name:  "go-template",
+				Usage: "Render each log with a Go text/template (e.g., '{{color .level .msg}}{{range .errors}}\n  - {{.code}}{{end}}')",
+			},
+			&cli.StringSliceFlag{
+				Name:  "fields",
+				Usage: "Show fields as aligned columns with a header row (e.g., timestamp,level,message)",
+			},
 			&cli.IntFlag{
 				Name:  "max-depth",
 				Usage: "Maximum depth for JSON parsing inside message field",
@@ -54,19 +65,110 @@ func NewRootCommand() *cli.Command {
 				Usage: "Hide missing fields when --format is specified",
 				Value: false,
 			},
+			&cli.BoolFlag{
+				Name:  "raw-level",
+				Usage: "Show levels as written instead of by their normalized names (e.g., 30 rather than INFO)",
+				Value: false,
+			},
 			&cli.BoolFlag{
 				Name:    "auto-convert-level",
 				Aliases: []string{"c"},
-				Usage:   "Automatically convert numeric log levels to text format",
+				Usage:   "Deprecated: show levels by their normalized names even when the profile sets \"raw_level\"",
 				Value:   false,
 			},
+			&cli.StringFlag{
+				Name:  "query",
+				Usage: "Apply a saved query (see 'jclog config add-query')",
+			},
 			&cli.StringSliceFlag{
 				Name:  "filter",
-				Usage: "Only show logs that match the specified field=value conditions",
+				Usage: "Only show logs that match the specified conditions (e.g., level=ERROR, duration_ms>1000, msg=~timeout)",
 			},
 			&cli.StringSliceFlag{
 				Name:  "exclude",
-				Usage: "Hide logs that match the specified field=value conditions",
+				Usage: "Hide logs that match the specified conditions (operators: = != > >= < <= =~)",
+			},
+			&cli.StringFlag{
+				Name:  "where",
+				Usage: "Only show logs matching a boolean expression (e.g., 'level == \"ERROR\" || http_code >= 500')",
+			},
+			&cli.StringFlag{
+				Name:  "min-level",
+				Usage: "Only show logs at or above the specified level (e.g., WARN shows WARN, ERROR and FATAL)",
+			},
+			&cli.StringFlag{
+				Name:  "max-level",
+				Usage: "Only show logs at or below the specified level",
+			},
+			&cli.StringFlag{
+				Name:  "since",
+				Usage: "Only show logs at or after a time (e.g., 2024-03-20T10:02:00Z, 10:02, 15m, 2h, today)",
+			},
+			&cli.StringFlag{
+				Name:  "until",
+				Usage: "Only show logs at or before a time (same formats as --since)",
+			},
+			&cli.StringFlag{
+				Name:  "tz",
+				Usage: "Show timestamps in a timezone (e.g., Asia/Tokyo, Europe/Berlin, UTC)",
+			},
+			&cli.BoolFlag{
+				Name:  "utc",
+				Usage: "Show timestamps in UTC (same as --tz UTC)",
+			},
+			&cli.StringFlag{
+				Name:  "input-tz",
+				Usage: "Timezone of timestamps written without an offset (default: UTC)",
+			},
+			&cli.StringFlag{
+				Name:  "time-field",
+				Usage: "Field holding the timestamp, when it is not timestamp, time or ts (e.g., created_at)",
+			},
+			&cli.StringFlag{
+				Name:  "gap-threshold",
+				Usage: "Highlight {timestamp|since} and {timestamp|delta} when the gap from the previous log is longer (default: 1s, 0 disables)",
+			},
+			&cli.StringFlag{
+				Name:  "missing-time",
+				Usage: "How to treat logs without a parseable timestamp when --since/--until is set: hide or show",
+				Value: "hide",
+			},
+			&cli.StringFlag{
+				Name:  "grep",
+				Usage: "Only show logs where any field value matches a regular expression",
+			},
+			&cli.BoolFlag{
+				Name:    "ignore-case",
+				Aliases: []string{"i"},
+				Usage:   "Ignore case distinctions in --grep",
+			},
+			&cli.BoolFlag{
+				Name:    "invert-match",
+				Aliases: []string{"v"},
+				Usage:   "Only show logs where no field value matches --grep",
+			},
+			&cli.IntFlag{
+				Name:    "after-context",
+				Aliases: []string{"A"},
+				Usage:   "Print `NUM` filtered-out logs after each match",
+			},
+			&cli.IntFlag{
+				Name:    "before-context",
+				Aliases: []string{"B"},
+				Usage:   "Print `NUM` filtered-out logs before each match",
+			},
+			&cli.IntFlag{
+				Name:    "context",
+				Aliases: []string{"C"},
+				Usage:   "Print `NUM` filtered-out logs before and after each match",
+			},
+			&cli.StringFlag{
+				Name:  "context-field",
+				Usage: "Only use logs with the same value of this field as context (e.g., request_id)",
+			},
+			&cli.StringFlag{
+				Name:  "jq",
+				Usage: "Select and reshape each log entry with a jq expression (e.g., 'select(.status >= 500) | {time, msg, status}')",
 			},
 		},
 		Commands: []*cli.Command{
@@ -93,6 +195,16 @@ func NewRootCommand() *cli.Command {
 			}
 			activeProfile := cfg.GetActiveProfile()
 
+			// Get saved query; its conditions take precedence over the profile's
+			var query config.Query
+			if name := cmd.String("query"); name != "" {
+				q, exists := cfg.Queries[name]
+				if !exists {
+					return fmt.Errorf("query '%s' does not exist", name)
+				}
+				query = q
+			}
+
 			// Get format from template or format flag
 			format := cmd.String("format")
 			if template := cmd.String("template"); template != "" {
@@ -102,13 +214,65 @@ func NewRootCommand() *cli.Command {
 					return fmt.Errorf("unknown template: %s", template)
 				}
 			}
-			if format == "" {
-				format = activeProfile.Format
+			goTemplateText := cmd.String("go-template")
+			if format != "" && goTemplateText != "" {
+				return fmt.Errorf("--go-template cannot be combined with --format or --template")
+			}
+			fields := splitFieldArgs(cmd.StringSlice("fields"))
+			if len(fields) > 0 && (format != "" || goTemplateText != "") {
+				return fmt.Errorf("--fields cannot be combined with --format, --template or --go-template")
 			}
-			if format == "" {
+			if format == "" && goTemplateText == "" && len(fields) == 0 {
+				switch activeProfile.TemplateEngine {
+				case "", "jclog":
+					format = activeProfile.Format
+				case "go":
+					goTemplateText = activeProfile.Format
+				default:
+					return fmt.Errorf("unknown template engine %q: expected jclog or go", activeProfile.TemplateEngine)
+				}
+				// A profile without a format shows its fields as columns
+				if format == "" && goTemplateText == "" {
+					fields = activeProfile.Fields
+				}
+			}
+			if format == "" && goTemplateText == "" && len(fields) == 0 {
 				format = builtinTemplates["basic"] // Use default template
 			}
 
+			displayTZ := cmd.String("tz")
+			if cmd.Bool("utc") {
+				if displayTZ != "" {
+					return fmt.Errorf("--utc cannot be combined with --tz")
+				}
+				displayTZ = "UTC"
+			}
+			timeSettings, err := newTimeSettings(activeProfile, displayTZ, cmd.String("input-tz"), cmd.String("time-field"))
+			if err != nil {
+				return err
+			}
+
+			levels, err := logparser.NewLevels(activeProfile.LevelMappings, activeProfile.LevelTables)
+			if err != nil {
+				return err
+			}
+
+			var goTemplate *logparser.GoTemplate
+			switch {
+			case goTemplateText != "":
+				if goTemplate, err = logparser.ParseGoTemplate(goTemplateText, timeSettings, levels); err != nil {
+					return err
+				}
+			case len(fields) > 0:
+				if err := logparser.ValidateFields(fields); err != nil {
+					return err
+				}
+			default:
+				if err := logparser.ValidateFormat(format); err != nil {
+					return err
+				}
+			}
+
 			maxDepth := int(cmd.Int("max-depth"))
 			if !cmd.IsSet("max-depth") {
 				maxDepth = activeProfile.MaxDepth
@@ -119,19 +283,108 @@ func NewRootCommand() *cli.Command {
 				hideMissing = activeProfile.HideMissing
 			}
 
-			autoConvertLevel := cmd.Bool("auto-convert-level")
-			if !cmd.IsSet("auto-convert-level") {
-				autoConvertLevel = activeProfile.AutoConvertLevel
+			// -c still normalizes levels for profiles that show them as written,
+			// such as those migrated from "auto_convert_level": false
+			rawLevel := cmd.Bool("raw-level")
+			if !cmd.IsSet("raw-level") {
+				rawLevel = activeProfile.RawLevel && !cmd.Bool("auto-convert-level")
+			}
+			if cmd.IsSet("auto-convert-level") {
+				fmt.Fprintln(os.Stderr, "warning: --auto-convert-level is deprecated; levels are normalized by default, and -c only overrides the profile's \"raw_level\"")
 			}
 
-			filters := parseFilterArgs(cmd.StringSlice("filter"))
-			if len(filters) == 0 {
-				filters = parseFilterArgs(activeProfile.Filters)
+			filterArgs := cmd.StringSlice("filter")
+			if len(filterArgs) == 0 {
+				filterArgs = query.Filters
+			}
+			if len(filterArgs) == 0 {
+				filterArgs = activeProfile.Filters
+			}
+			filters, err := parseFilterArgs(filterArgs)
+			if err != nil {
+				return err
+			}
+
+			excludeArgs := cmd.StringSlice("exclude")
+			if len(excludeArgs) == 0 {
+				excludeArgs = query.Excludes
+			}
+			if len(excludeArgs) == 0 {
+				excludeArgs = activeProfile.Excludes
+			}
+			excludes, err := parseFilterArgs(excludeArgs)
+			if err != nil {
+				return err
+			}
+
+			whereExpr := cmd.String("where")
+			if whereExpr == "" {
+				whereExpr = query.Where
+			}
+			if whereExpr == "" {
+				whereExpr = activeProfile.Where
+			}
+			var where *logparser.Where
+			if whereExpr != "" {
+				if where, err = logparser.ParseWhere(whereExpr); err != nil {
+					return err
+				}
+			}
+
+			minLevel, err := parseLevelArg(levels, cmd.String("min-level"), activeProfile.MinLevel)
+			if err != nil {
+				return err
+			}
+			maxLevel, err := parseLevelArg(levels, cmd.String("max-level"), activeProfile.MaxLevel)
+			if err != nil {
+				return err
+			}
+
+			// --since/--until times without an offset are in the display timezone
+			now := time.Now().In(timeSettings.Location)
+			since, err := parseTimeBoundArg(cmd.String("since"), query.Since, now)
+			if err != nil {
+				return err
+			}
+			until, err := parseTimeBoundArg(cmd.String("until"), query.Until, now)
+			if err != nil {
+				return err
+			}
+
+			gapThreshold, err := parseGapThreshold(cmd.String("gap-threshold"), activeProfile.GapThreshold)
+			if err != nil {
+				return err
 			}
 
-			excludes := parseFilterArgs(cmd.StringSlice("exclude"))
-			if len(excludes) == 0 {
-				excludes = parseFilterArgs(activeProfile.Excludes)
+			missingTime := cmd.String("missing-time")
+			if missingTime != "hide" && missingTime != "show" {
+				return fmt.Errorf("invalid --missing-time value %q: expected hide or show", missingTime)
+			}
+
+			var grep *logparser.Grep
+			if pattern := cmd.String("grep"); pattern != "" {
+				if grep, err = logparser.NewGrep(pattern, cmd.Bool("ignore-case"), cmd.Bool("invert-match")); err != nil {
+					return err
+				}
+			}
+
+			beforeContext := int(cmd.Int("context"))
+			afterContext := beforeContext
+			if cmd.IsSet("before-context") {
+				beforeContext = int(cmd.Int("before-context"))
+			}
+			if cmd.IsSet("after-context") {
+				afterContext = int(cmd.Int("after-context"))
+			}
+			if beforeContext < 0 || afterContext < 0 {
+				return fmt.Errorf("context line count must not be negative")
+			}
+
+			var jq *logparser.JQ
+			if jqExpr := cmd.String("jq"); jqExpr != "" {
+				if jq, err = logparser.ParseJQ(jqExpr); err != nil {
+					return err
+				}
 			}
 
 			var scanner *bufio.Scanner
@@ -151,20 +404,145 @@ func NewRootCommand() *cli.Command {
 			}
 
 			// Process logs
-			logparser.ProcessLog(scanner, format, maxDepth, hideMissing, filters, excludes, activeProfile.LevelMappings, autoConvertLevel, activeProfile.TimeFormat)
+			logparser.ProcessLog(scanner, logparser.Options{
+				Format:          format,
+				GoTemplate:      goTemplate,
+				Fields:          fields,
+				MaxDepth:        maxDepth,
+				HideMissing:     hideMissing,
+				Filters:         filters,
+				Excludes:        excludes,
+				Where:           where,
+				JQ:              jq,
+				MinLevel:        minLevel,
+				MaxLevel:        maxLevel,
+				Since:           since,
+				Until:           until,
+				KeepMissingTime: missingTime == "show",
+				Grep:            grep,
+				BeforeContext:   beforeContext,
+				AfterContext:    afterContext,
+				ContextField:    cmd.String("context-field"),
+				Levels:          levels,
+				RawLevel:        rawLevel,
+				Time:            timeSettings,
+				GapThreshold:    gapThreshold,
+			})
 			return nil
 		},
 	}
 }
 
-// parseFilterArgs converts "key=value" strings into a map
-func parseFilterArgs(args []string) map[string]string {
-	filters := make(map[string]string)
+// parseLevelArg returns the level of the flag value, falling back to the profile value
+func parseLevelArg(levels *logparser.Levels, flagValue, profileValue string) (logparser.Level, error) {
+	level := flagValue
+	if level == "" {
+		level = profileValue
+	}
+	if level == "" {
+		return logparser.LevelUnknown, nil
+	}
+	return levels.Parse(level)
+}
+
+// newTimeSettings combines the profile's timestamp settings with the
+// timezone and time field flags, which take precedence
+func newTimeSettings(profile config.Profile, displayTZ, inputTZ, timeField string) (logparser.TimeSettings, error) {
+	epochUnit, err := logparser.ParseEpochUnit(profile.EpochPrecision)
+	if err != nil {
+		return logparser.TimeSettings{}, err
+	}
+	displayLoc, err := loadTimezoneArg(displayTZ, profile.Timezone, "Local")
+	if err != nil {
+		return logparser.TimeSettings{}, err
+	}
+	inputLoc, err := loadTimezoneArg(inputTZ, profile.InputTimezone, "UTC")
+	if err != nil {
+		return logparser.TimeSettings{}, err
+	}
+	for _, layout := range profile.TimeInputLayouts {
+		if err := logparser.ValidateTimeLayout(layout); err != nil {
+			return logparser.TimeSettings{}, err
+		}
+	}
+	if timeField == "" {
+		timeField = profile.TimeField
+	}
+	return logparser.TimeSettings{
+		Format:        profile.TimeFormat,
+		EpochUnit:     epochUnit,
+		Location:      displayLoc,
+		InputLocation: inputLoc,
+		Layouts:       logparser.NewTimeLayouts(profile.TimeInputLayouts),
+		Field:         timeField,
+	}, nil
+}
+
+// parseGapThreshold reads the gap threshold from the flag, falling back to
+// the profile and then to one second
+func parseGapThreshold(flagValue, profileValue string) (time.Duration, error) {
+	value := flagValue
+	if value == "" {
+		value = profileValue
+	}
+	if value == "" {
+		return time.Second, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid gap threshold %q: expected a duration such as 500ms or 2s", value)
+	}
+	return d, nil
+}
+
+// loadTimezoneArg loads the timezone named by the flag value, falling back
+// to the profile value and then to the default
+func loadTimezoneArg(flagValue, profileValue, defaultName string) (*time.Location, error) {
+	name := flagValue
+	if name == "" {
+		name = profileValue
+	}
+	if name == "" {
+		name = defaultName
+	}
+	return logparser.LoadTimezone(name)
+}
+
+// parseTimeBoundArg parses the flag value, falling back to the saved query value
+func parseTimeBoundArg(flagValue, queryValue string, now time.Time) (time.Time, error) {
+	value := flagValue
+	if value == "" {
+		value = queryValue
+	}
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return logparser.ParseTimeBound(value, now)
+}
+
+// splitFieldArgs splits comma-separated --fields values, so
+// "--fields time,level" and "--fields time --fields level" are the same
+func splitFieldArgs(args []string) []string {
+	fields := make([]string, 0, len(args))
+	for _, arg := range args {
+		for _, field := range strings.Split(arg, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				fields = append(fields, field)
+			}
+		}
+	}
+	return fields
+}
+
+// parseFilterArgs converts "field<op>value" strings into filters
+func parseFilterArgs(args []string) ([]logparser.Filter, error) {
+	filters := make([]logparser.Filter, 0, len(args))
 	for _, arg := range args {
-		parts := strings.SplitN(arg, "=", 2)
-		if len(parts) == 2 {
-			filters[parts[0]] = parts[1]
+		filter, err := logparser.ParseFilter(arg)
+		if err != nil {
+			return nil, err
 		}
+		filters = append(filters, filter)
 	}
-	return filters
+	return filters, nil
 }
@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/techarm/jclog/internal/config"
@@ -13,52 +14,97 @@ import (
 
 func TestParseFilterArgs(t *testing.T) {
 	tests := []struct {
-		name string
-		args []string
-		want map[string]string
+		name    string
+		args    []string
+		want    []string
+		wantErr bool
 	}{
 		{
 			name: "Empty arguments",
 			args: []string{},
-			want: map[string]string{},
+			want: []string{},
 		},
 		{
 			name: "Single filter",
 			args: []string{"level=INFO"},
-			want: map[string]string{
-				"level": "INFO",
-			},
+			want: []string{"level=INFO"},
 		},
 		{
 			name: "Multiple filters",
 			args: []string{"level=INFO", "env=prod"},
-			want: map[string]string{
-				"level": "INFO",
-				"env":   "prod",
-			},
+			want: []string{"level=INFO", "env=prod"},
+		},
+		{
+			name: "Comparison operators",
+			args: []string{"duration_ms>1000", "cpu_usage>=80", "level!=DEBUG", "msg=~timeout|refused"},
+			want: []string{"duration_ms>1000", "cpu_usage>=80", "level!=DEBUG", "msg=~timeout|refused"},
 		},
 		{
-			name: "Invalid format",
-			args: []string{"invalid"},
-			want: map[string]string{},
+			name:    "Invalid format",
+			args:    []string{"invalid"},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid regex",
+			args:    []string{"msg=~("},
+			wantErr: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := parseFilterArgs(tt.args)
+			got, err := parseFilterArgs(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseFilterArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
 			if len(got) != len(tt.want) {
-				t.Errorf("parseFilterArgs() got %v, want %v", got, tt.want)
+				t.Fatalf("parseFilterArgs() got %v, want %v", got, tt.want)
 			}
-			for k, v := range tt.want {
-				if got[k] != v {
-					t.Errorf("parseFilterArgs() got[%s] = %v, want %v", k, got[k], v)
+			for i, want := range tt.want {
+				if got[i].String() != want {
+					t.Errorf("parseFilterArgs() got[%d] = %v, want %v", i, got[i], want)
 				}
 			}
 		})
 	}
 }
 
+func TestSplitFieldArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "Comma-separated",
+			args: []string{"timestamp,level:>5,message|upper"},
+			want: []string{"timestamp", "level:>5", "message|upper"},
+		},
+		{
+			name: "Repeated flags",
+			args: []string{"timestamp", "level, message"},
+			want: []string{"timestamp", "level", "message"},
+		},
+		{
+			name: "Empty entries are dropped",
+			args: []string{"timestamp,,", ""},
+			want: []string{"timestamp"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitFieldArgs(tt.args)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("splitFieldArgs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestRootCommand(t *testing.T) {
 	// Save original args and restore them after test
 	oldArgs := os.Args
@@ -82,6 +128,64 @@ func TestRootCommand(t *testing.T) {
 		Filters:     []string{"level=INFO"},
 		Excludes:    []string{},
 	}
+	testConfig.Profiles["prod"] = config.Profile{
+		Format:   "{timestamp} [{level}] {message}",
+		MaxDepth: 2,
+		Filters:  []string{"level=ERROR", "level=WARN"},
+		Excludes: []string{"level=DEBUG"},
+	}
+	testConfig.Profiles["gotmpl"] = config.Profile{
+		Format:         `{{time .timestamp}} {{color .level .level}} {{.message}}`,
+		TemplateEngine: "go",
+		MaxDepth:       2,
+	}
+	testConfig.Profiles["columns"] = config.Profile{
+		Fields:   []string{"timestamp", "level", "message"},
+		MaxDepth: 2,
+	}
+	testConfig.Profiles["tokyo"] = config.Profile{
+		Format:        "{timestamp} {message}",
+		TimeFormat:    "2006-01-02 15:04:05",
+		Timezone:      "Asia/Tokyo",
+		InputTimezone: "UTC",
+	}
+	testConfig.Profiles["apache"] = config.Profile{
+		Format:           "{timestamp} {message}",
+		TimeInputLayouts: []string{"02/Jan/2006:15:04:05 -0700"},
+		TimeField:        "created_at",
+	}
+	testConfig.Profiles["bad-layout"] = config.Profile{
+		Format:           "{timestamp}",
+		TimeInputLayouts: []string{"dd/MM/yyyy"},
+	}
+	testConfig.Profiles["levels"] = config.Profile{
+		Format:      "{timestamp} [{level}] {message}",
+		RawLevel:    true,
+		MinLevel:    "verbose",
+		LevelTables: map[string]map[string]string{"names": {"verbose": "TRACE"}},
+	}
+	testConfig.Profiles["bad-levels"] = config.Profile{
+		Format:      "{timestamp}",
+		LevelTables: map[string]map[string]string{"log4j": {"1": "INFO"}},
+	}
+	testConfig.Profiles["bad-tz"] = config.Profile{
+		Format:   "{timestamp}",
+		Timezone: "Mars/Olympus",
+	}
+	testConfig.Profiles["bad-engine"] = config.Profile{
+		Format:         "{timestamp}",
+		TemplateEngine: "jinja",
+	}
+	autoConvertLevel := true
+	testConfig.Profiles["legacy-levels"] = config.Profile{
+		Format:           "{timestamp} [{level}] {message}",
+		AutoConvertLevel: &autoConvertLevel,
+	}
+	testConfig.Queries["info-today"] = config.Query{
+		Filters: []string{"level=INFO"},
+		Where:   `message contains "test"`,
+		Since:   "2024-03-20",
+	}
 	if err := config.SaveConfig(testConfig, configPath); err != nil {
 		t.Fatalf("Failed to save test config: %v", err)
 	}
@@ -126,6 +230,251 @@ func TestRootCommand(t *testing.T) {
 			args:    []string{"jclog", "--config", configPath, "--profile", "test", logPath},
 			wantErr: false,
 		},
+		{
+			name:    "With repeated profile filters",
+			args:    []string{"jclog", "--config", configPath, "--profile", "prod", logPath},
+			wantErr: false,
+		},
+		{
+			name:    "With value set filter",
+			args:    []string{"jclog", "--config", configPath, "--filter", "level=INFO,DEBUG", logPath},
+			wantErr: false,
+		},
+		{
+			name:    "Regex filter with commas",
+			args:    []string{"jclog", "--config", configPath, "--filter", "message=~^(test|debug),? message$", logPath},
+			wantErr: false,
+		},
+		{
+			name:    "With where expression",
+			args:    []string{"jclog", "--config", configPath, "--where", `level == "INFO" && !(message contains "debug")`, logPath},
+			wantErr: false,
+		},
+		{
+			name:    "With modifiers",
+			args:    []string{"jclog", "--config", configPath, "--format", "{timestamp} [{level|upper|pad:5}] {message|trunc:40} {user|default:anonymous}", logPath},
+			wantErr: false,
+		},
+		{
+			name:    "Unknown modifier",
+			args:    []string{"jclog", "--config", configPath, "--format", "{level|shout}", logPath},
+			wantErr: true,
+		},
+		{
+			name:    "With format specs",
+			args:    []string{"jclog", "--config", configPath, "--format", "[{level:<5}] {message:40} {duration_ms:>8.2f}", logPath},
+			wantErr: false,
+		},
+		{
+			name:    "Invalid format spec",
+			args:    []string{"jclog", "--config", configPath, "--format", "{level:wide}", logPath},
+			wantErr: true,
+		},
+		{
+			name:    "With go template",
+			args:    []string{"jclog", "--config", configPath, "--go-template", `{{.level | default "-"}} {{json .}}{{range .tags}} {{.}}{{end}}`, logPath},
+			wantErr: false,
+		},
+		{
+			name:    "With go template profile",
+			args:    []string{"jclog", "--config", configPath, "--profile", "gotmpl", logPath},
+			wantErr: false,
+		},
+		{
+			name:    "Invalid go template",
+			args:    []string{"jclog", "--config", configPath, "--go-template", "{{.level", logPath},
+			wantErr: true,
+		},
+		{
+			name:    "Go template with format",
+			args:    []string{"jclog", "--config", configPath, "--go-template", "{{.level}}", "--format", "{level}", logPath},
+			wantErr: true,
+		},
+		{
+			name:    "With fields",
+			args:    []string{"jclog", "--config", configPath, "--fields", "timestamp,level:>5,message|upper", logPath},
+			wantErr: false,
+		},
+		{
+			name:    "With fields profile",
+			args:    []string{"jclog", "--config", configPath, "--profile", "columns", logPath},
+			wantErr: false,
+		},
+		{
+			name:    "Fields with format",
+			args:    []string{"jclog", "--config", configPath, "--fields", "level", "--format", "{level}", logPath},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid field modifier",
+			args:    []string{"jclog", "--config", configPath, "--fields", "level|shout", logPath},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown template engine",
+			args:    []string{"jclog", "--config", configPath, "--profile", "bad-engine", logPath},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid where expression",
+			args:    []string{"jclog", "--config", configPath, "--where", `level ==`, logPath},
+			wantErr: true,
+		},
+		{
+			name:    "With jq expression",
+			args:    []string{"jclog", "--config", configPath, "--jq", `select(.level == "INFO") | {timestamp, level, message}`, logPath},
+			wantErr: false,
+		},
+		{
+			name:    "Invalid jq expression",
+			args:    []string{"jclog", "--config", configPath, "--jq", `select(`, logPath},
+			wantErr: true,
+		},
+		{
+			name:    "With level bounds",
+			args:    []string{"jclog", "--config", configPath, "--min-level", "debug", "--max-level", "warning", logPath},
+			wantErr: false,
+		},
+		{
+			name:    "With profile level tables",
+			args:    []string{"jclog", "--config", configPath, "--profile", "levels", "--filter", "level=warning", logPath},
+			wantErr: false,
+		},
+		{
+			name:    "With raw level",
+			args:    []string{"jclog", "--config", configPath, "--raw-level", logPath},
+			wantErr: false,
+		},
+		{
+			name:    "With deprecated auto convert level",
+			args:    []string{"jclog", "--config", configPath, "-c", logPath},
+			wantErr: false,
+		},
+		{
+			name:    "With deprecated auto_convert_level profile key",
+			args:    []string{"jclog", "--config", configPath, "--profile", "legacy-levels", logPath},
+			wantErr: false,
+		},
+		{
+			name:    "Invalid profile level table",
+			args:    []string{"jclog", "--config", configPath, "--profile", "bad-levels", logPath},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid level",
+			args:    []string{"jclog", "--config", configPath, "--min-level", "verbose", logPath},
+			wantErr: true,
+		},
+		{
+			name:    "With time window",
+			args:    []string{"jclog", "--config", configPath, "--since", "2024-03-19", "--until", "now", "--missing-time", "show", logPath},
+			wantErr: false,
+		},
+		{
+			name:    "Invalid time window",
+			args:    []string{"jclog", "--config", configPath, "--since", "a while ago", logPath},
+			wantErr: true,
+		},
+		{
+			name:    "With display timezone",
+			args:    []string{"jclog", "--config", configPath, "--tz", "Asia/Tokyo", "--input-tz", "Europe/Berlin", "--since", "2024-03-19", logPath},
+			wantErr: false,
+		},
+		{
+			name:    "With relative timestamps",
+			args:    []string{"jclog", "--config", configPath, "--format", "{timestamp|delta} {timestamp|since} {message}", "--gap-threshold", "250ms", logPath},
+			wantErr: false,
+		},
+		{
+			name:    "Invalid gap threshold",
+			args:    []string{"jclog", "--config", configPath, "--gap-threshold", "a bit", logPath},
+			wantErr: true,
+		},
+		{
+			name:    "With UTC",
+			args:    []string{"jclog", "--config", configPath, "--utc", logPath},
+			wantErr: false,
+		},
+		{
+			name:    "With profile timezone",
+			args:    []string{"jclog", "--config", configPath, "--profile", "tokyo", logPath},
+			wantErr: false,
+		},
+		{
+			name:    "With time field",
+			args:    []string{"jclog", "--config", configPath, "--time-field", "created_at", "--since", "2024-03-19", logPath},
+			wantErr: false,
+		},
+		{
+			name:    "With profile time layouts",
+			args:    []string{"jclog", "--config", configPath, "--profile", "apache", logPath},
+			wantErr: false,
+		},
+		{
+			name:    "Invalid profile time layout",
+			args:    []string{"jclog", "--config", configPath, "--profile", "bad-layout", logPath},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid profile timezone",
+			args:    []string{"jclog", "--config", configPath, "--profile", "bad-tz", logPath},
+			wantErr: true,
+		},
+		{
+			name:    "UTC with timezone",
+			args:    []string{"jclog", "--config", configPath, "--utc", "--tz", "Asia/Tokyo", logPath},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid timezone",
+			args:    []string{"jclog", "--config", configPath, "--tz", "Mars/Olympus", logPath},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid missing time policy",
+			args:    []string{"jclog", "--config", configPath, "--since", "15m", "--missing-time", "maybe", logPath},
+			wantErr: true,
+		},
+		{
+			name:    "With grep",
+			args:    []string{"jclog", "--config", configPath, "--grep", "TEST", "-i", "-v", logPath},
+			wantErr: false,
+		},
+		{
+			name:    "Invalid grep pattern",
+			args:    []string{"jclog", "--config", configPath, "--grep", "(", logPath},
+			wantErr: true,
+		},
+		{
+			name:    "With context lines",
+			args:    []string{"jclog", "--config", configPath, "--filter", "level=DEBUG", "-B", "1", "-A", "2", logPath},
+			wantErr: false,
+		},
+		{
+			name:    "With correlated context",
+			args:    []string{"jclog", "--config", configPath, "--filter", "level=DEBUG", "-C", "3", "--context-field", "request_id", logPath},
+			wantErr: false,
+		},
+		{
+			name:    "Negative context",
+			args:    []string{"jclog", "--config", configPath, "-C", "-1", logPath},
+			wantErr: true,
+		},
+		{
+			name:    "With saved query",
+			args:    []string{"jclog", "--config", configPath, "--query", "info-today", logPath},
+			wantErr: false,
+		},
+		{
+			name:    "Unknown saved query",
+			args:    []string{"jclog", "--config", configPath, "--query", "nope", logPath},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid filter",
+			args:    []string{"jclog", "--config", configPath, "--filter", "level", logPath},
+			wantErr: true,
+		},
 		{
 			name:    "Invalid file",
 			args:    []string{"jclog", "--config", configPath, "nonexistent.log"},
@@ -144,3 +493,70 @@ func TestRootCommand(t *testing.T) {
 		})
 	}
 }
+
+func TestAutoConvertLevelWithBaselineConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// A config as written by config init before raw_level existed
+	configPath := filepath.Join(tmpDir, "config.json")
+	baseline := `{
+  "active_profile": "default",
+  "profiles": {
+    "default": {
+      "format": "[{level}] {msg}",
+      "fields": null,
+      "max_depth": 2,
+      "hide_missing": false,
+      "filters": [],
+      "excludes": [],
+      "level_mappings": {"30": "INFO", "50": "ERROR"},
+      "auto_convert_level": false,
+      "time_format": "2006/01/02 15:04:05.000"
+    }
+  }
+}`
+	if err := os.WriteFile(configPath, []byte(baseline), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	logPath := filepath.Join(tmpDir, "bunyan.log")
+	if err := os.WriteFile(logPath, []byte(`{"level": 30, "msg": "started"}`+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write log: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "Profile shows levels as written", args: []string{"jclog", "--config", configPath, logPath}, want: "[30] started"},
+		{name: "Auto convert level overrides the profile", args: []string{"jclog", "--config", configPath, "-c", logPath}, want: "[INFO] started"},
+		{name: "Raw level wins over auto convert level", args: []string{"jclog", "--config", configPath, "-c", "--raw-level", logPath}, want: "[30] started"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldStdout, oldStderr := os.Stdout, os.Stderr
+			r, w, _ := os.Pipe()
+			os.Stdout = w
+			os.Stderr, _ = os.Open(os.DevNull)
+			outC := make(chan string)
+			go func() {
+				var buf bytes.Buffer
+				io.Copy(&buf, r)
+				outC <- buf.String()
+			}()
+
+			err := NewRootCommand().Run(context.Background(), tt.args)
+			w.Close()
+			os.Stderr.Close()
+			os.Stdout, os.Stderr = oldStdout, oldStderr
+			out := <-outC
+			if err != nil {
+				t.Fatalf("RootCommand() error = %v", err)
+			}
+			if !strings.Contains(out, tt.want) {
+				t.Errorf("output = %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
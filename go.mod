@@ -4,10 +4,12 @@ go 1.22.5
 
 require (
 	github.com/fatih/color v1.18.0
+	github.com/itchyny/gojq v0.12.17
 	github.com/urfave/cli/v3 v3.0.0-beta1
 )
 
 require (
+	github.com/itchyny/timefmt-go v0.1.6 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	golang.org/x/sys v0.25.0 // indirect
@@ -11,19 +11,45 @@ import (
 type Config struct {
 	ActiveProfile string             `json:"active_profile"`
 	Profiles      map[string]Profile `json:"profiles"`
+	Queries       map[string]Query   `json:"queries"`
 }
 
 // Profile represents a single configuration profile
 type Profile struct {
-	Format           string            `json:"format"`
-	Fields           []string          `json:"fields"`
-	MaxDepth         int               `json:"max_depth"`
-	HideMissing      bool              `json:"hide_missing"`
-	Filters          []string          `json:"filters"`
-	Excludes         []string          `json:"excludes"`
-	LevelMappings    map[string]string `json:"level_mappings"`
-	AutoConvertLevel bool              `json:"auto_convert_level"`
-	TimeFormat       string            `json:"time_format"`
+	Format           string                       `json:"format"`
+	TemplateEngine   string                       `json:"template_engine"`
+	Fields           []string                     `json:"fields"`
+	MaxDepth         int                          `json:"max_depth"`
+	HideMissing      bool                         `json:"hide_missing"`
+	Filters          []string                     `json:"filters"`
+	Excludes         []string                     `json:"excludes"`
+	Where            string                       `json:"where"`
+	MinLevel         string                       `json:"min_level"`
+	MaxLevel         string                       `json:"max_level"`
+	LevelMappings    map[string]string            `json:"level_mappings"`
+	LevelTables      map[string]map[string]string `json:"level_tables"`
+	RawLevel         bool                         `json:"raw_level"`
+	TimeFormat       string                       `json:"time_format"`
+	TimeInputLayouts []string                     `json:"time_input_layouts"`
+	TimeField        string                       `json:"time_field"`
+	EpochPrecision   string                       `json:"epoch_precision"`
+	Timezone         string                       `json:"timezone"`
+	InputTimezone    string                       `json:"input_timezone"`
+	GapThreshold     string                       `json:"gap_threshold"`
+
+	// AutoConvertLevel is no longer used: levels are normalized unless
+	// RawLevel is set. LoadConfig moves it to RawLevel and clears it, so
+	// saving drops it.
+	AutoConvertLevel *bool `json:"auto_convert_level,omitempty"`
+}
+
+// Query represents a saved bundle of filter conditions applied with --query
+type Query struct {
+	Filters  []string `json:"filters"`
+	Excludes []string `json:"excludes"`
+	Where    string   `json:"where"`
+	Since    string   `json:"since"`
+	Until    string   `json:"until"`
 }
 
 // DefaultConfig creates a new configuration with default values
@@ -32,13 +58,12 @@ func DefaultConfig() *Config {
 		ActiveProfile: "default",
 		Profiles: map[string]Profile{
 			"default": {
-				Format:           "{time} [{level}] {msg} ({name})",
-				MaxDepth:         2,
-				HideMissing:      false,
-				Filters:          []string{},
-				Excludes:         []string{},
-				AutoConvertLevel: false,
-				TimeFormat:       "2006/01/02 15:04:05.000",
+				Format:      "{time} [{level}] {msg} ({name})",
+				MaxDepth:    2,
+				HideMissing: false,
+				Filters:     []string{},
+				Excludes:    []string{},
+				TimeFormat:  "2006/01/02 15:04:05.000",
 				LevelMappings: map[string]string{
 					"10": "TRACE",
 					"20": "DEBUG",
@@ -49,6 +74,7 @@ func DefaultConfig() *Config {
 				},
 			},
 		},
+		Queries: map[string]Query{},
 	}
 }
 
@@ -70,9 +96,27 @@ func LoadConfig(configPath string) (*Config, error) {
 	}
 
 	// Ensure default profile exists
+	if config.Profiles == nil {
+		config.Profiles = make(map[string]Profile)
+	}
 	if _, exists := config.Profiles["default"]; !exists {
 		config.Profiles["default"] = DefaultConfig().Profiles["default"]
 	}
+	if config.Queries == nil {
+		config.Queries = make(map[string]Query)
+	}
+
+	// Older profiles showed levels as written unless auto_convert_level was true
+	for name, profile := range config.Profiles {
+		if profile.AutoConvertLevel == nil {
+			continue
+		}
+		if !*profile.AutoConvertLevel {
+			profile.RawLevel = true
+		}
+		profile.AutoConvertLevel = nil
+		config.Profiles[name] = profile
+	}
 
 	return &config, nil
 }
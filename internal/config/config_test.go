@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -71,12 +72,76 @@ func TestLoadConfig(t *testing.T) {
 		t.Errorf("Expected active profile to be 'test', got '%s'", loadedConfig.ActiveProfile)
 	}
 
+	if loadedConfig.Queries == nil {
+		t.Error("Expected queries map to be initialized")
+	}
+
 	testProfile := loadedConfig.Profiles["test"]
 	if testProfile.Format != "{timestamp} {message}" {
 		t.Errorf("Expected format '{timestamp} {message}', got '%s'", testProfile.Format)
 	}
 }
 
+func TestLoadConfigMigratesAutoConvertLevel(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.json")
+
+	// A config as written by config init before raw_level existed
+	legacy := `{
+  "active_profile": "default",
+  "profiles": {
+    "default": {
+      "format": "{time} [{level}] {msg} ({name})",
+      "fields": null,
+      "max_depth": 2,
+      "hide_missing": false,
+      "filters": [],
+      "excludes": [],
+      "level_mappings": {"30": "INFO", "50": "ERROR"},
+      "auto_convert_level": false,
+      "time_format": "2006/01/02 15:04:05.000"
+    },
+    "converted": {
+      "format": "{level} {msg}",
+      "auto_convert_level": true
+    }
+  }
+}`
+	if err := os.WriteFile(configPath, []byte(legacy), 0644); err != nil {
+		t.Fatalf("Failed to write legacy config: %v", err)
+	}
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load legacy config: %v", err)
+	}
+	if p := cfg.Profiles["default"]; !p.RawLevel || p.AutoConvertLevel != nil {
+		t.Errorf("auto_convert_level false: RawLevel = %v, AutoConvertLevel = %v, want true, nil", p.RawLevel, p.AutoConvertLevel)
+	}
+	if p := cfg.Profiles["converted"]; p.RawLevel || p.AutoConvertLevel != nil {
+		t.Errorf("auto_convert_level true: RawLevel = %v, AutoConvertLevel = %v, want false, nil", p.RawLevel, p.AutoConvertLevel)
+	}
+
+	// Saving drops the key and keeps the migrated setting
+	if err := SaveConfig(cfg, configPath); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	if strings.Contains(string(data), "auto_convert_level") {
+		t.Errorf("Expected auto_convert_level to be dropped on save, got:\n%s", data)
+	}
+	saved, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to reload config: %v", err)
+	}
+	if !saved.Profiles["default"].RawLevel {
+		t.Error("Expected raw_level to survive saving")
+	}
+}
+
 func TestGetActiveProfile(t *testing.T) {
 	cfg := &Config{
 		ActiveProfile: "test",
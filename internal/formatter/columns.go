@@ -0,0 +1,91 @@
+package formatter
+
+import (
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// Widest a column grows to fit its values; the last column is never cut
+const maxColumnWidth = 60
+
+// Space between columns
+const columnGap = "  "
+
+var headerColor = color.New(color.Bold).SprintFunc()
+
+// Columns lays out rows as aligned columns under a header row. The first
+// rows, the sample window, are held back until they have sized the
+// columns; later rows keep those widths, cutting longer values with an
+// ellipsis, so that streamed output stays aligned without waiting for the
+// whole input.
+type Columns struct {
+	names  []string
+	widths []int
+	// sample is the number of rows that size the columns
+	sample int
+	// pending holds the sample rows until the widths settle
+	pending [][]string
+	settled bool
+}
+
+// NewColumns creates a layout for the named columns whose widths settle
+// after sample rows
+func NewColumns(names []string, sample int) *Columns {
+	widths := make([]int, len(names))
+	for i, name := range names {
+		widths[i] = min(DisplayWidth(name), maxColumnWidth)
+	}
+	return &Columns{names: names, widths: widths, sample: sample}
+}
+
+// Row adds a row of cells. Rows of the sample window are held back until
+// it is full, then returned with the header row, laid out with the settled
+// widths; later rows are returned right away.
+func (c *Columns) Row(cells []string) (header string, rows []string) {
+	if c.settled {
+		return "", []string{c.layout(cells)}
+	}
+	for i, cell := range cells {
+		if i < len(c.widths) {
+			c.widths[i] = max(c.widths[i], min(DisplayWidth(cell), maxColumnWidth))
+		}
+	}
+	c.pending = append(c.pending, cells)
+	if len(c.pending) < c.sample {
+		return "", nil
+	}
+	return c.Flush()
+}
+
+// Flush settles the widths before the sample window is full, as when the
+// input ends or pauses, and returns the header row and the held-back rows.
+// It returns nothing when no rows are held back.
+func (c *Columns) Flush() (header string, rows []string) {
+	if c.settled || len(c.pending) == 0 {
+		return "", nil
+	}
+	c.settled = true
+	rows = make([]string, len(c.pending))
+	for i, cells := range c.pending {
+		rows[i] = c.layout(cells)
+	}
+	c.pending = nil
+	return headerColor(c.layout(c.names)), rows
+}
+
+// layout pads each cell to its column width; the last column is left as is
+func (c *Columns) layout(cells []string) string {
+	var b strings.Builder
+	for i, cell := range cells {
+		if i > 0 {
+			b.WriteString(columnGap)
+		}
+		if i == len(cells)-1 || i >= len(c.widths) {
+			b.WriteString(cell)
+			continue
+		}
+		b.WriteString(PadWidth(TruncateWidth(cell, c.widths[i]), c.widths[i], '<'))
+	}
+	return strings.TrimRight(b.String(), " ")
+}
@@ -0,0 +1,73 @@
+package formatter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestColumns(t *testing.T) {
+	oldNoColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = oldNoColor }()
+
+	c := NewColumns([]string{"time", "level", "message"}, 3)
+	for _, cells := range [][]string{
+		{"10:00:01", "INFO", "started"},
+		{"10:00:02", "WARNING", "slow"},
+	} {
+		if header, rows := c.Row(cells); header != "" || len(rows) > 0 {
+			t.Fatalf("sample row %v printed before the widths settled: %q %q", cells, header, rows)
+		}
+	}
+
+	header, rows := c.Row([]string{"10:00:03", "CRITICAL", "a message longer than the header"})
+	if want := "time      level     message"; header != want {
+		t.Errorf("header = %q, want %q", header, want)
+	}
+	want := []string{
+		"10:00:01  INFO      started",
+		"10:00:02  WARNING   slow",
+		"10:00:03  CRITICAL  a message longer than the header",
+	}
+	if strings.Join(rows, "\n") != strings.Join(want, "\n") {
+		t.Errorf("sample rows = %q, want %q", rows, want)
+	}
+
+	for cells, want := range map[[3]string]string{
+		{"10:00:04", "EMERGENCY", "cut"}:  "10:00:04  EMERGEN…  cut",
+		{"10:00:05", "", "missing level"}: "10:00:05            missing level",
+	} {
+		header, rows := c.Row(cells[:])
+		if header != "" {
+			t.Errorf("row %v repeated the header %q", cells, header)
+		}
+		if len(rows) != 1 || rows[0] != want {
+			t.Errorf("row %v = %q, want %q", cells, rows, want)
+		}
+	}
+}
+
+func TestColumnsFlush(t *testing.T) {
+	oldNoColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = oldNoColor }()
+
+	c := NewColumns([]string{"user", "msg"}, 10)
+	if header, rows := c.Flush(); header != "" || rows != nil {
+		t.Errorf("Flush() without rows = %q %q", header, rows)
+	}
+	c.Row([]string{"山田", "こんにちは"})
+	c.Row([]string{"al", "hi"})
+	header, rows := c.Flush()
+	if want := []string{"山田  こんにちは", "al    hi"}; header != "user  msg" || strings.Join(rows, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Flush() = %q %q, want header and %q", header, rows, want)
+	}
+	if header, rows := c.Flush(); header != "" || rows != nil {
+		t.Errorf("second Flush() = %q %q", header, rows)
+	}
+	if _, rows := c.Row([]string{"bob", "after"}); len(rows) != 1 || rows[0] != "bob   after" {
+		t.Errorf("row after Flush() = %q", rows)
+	}
+}
@@ -1,81 +1,58 @@
 package formatter
 
 import (
+	"regexp"
 	"strings"
 
 	"github.com/fatih/color"
 )
 
-// Level color mapping
+// Level color mapping, by canonical level name
 var levelColors = map[string]func(a ...any) string{
-	"TRACE": color.New(color.FgHiWhite).SprintFunc(),
-	"DEBUG": color.New(color.FgHiBlack).SprintFunc(),
-	"INFO":  color.New(color.FgGreen).SprintFunc(),
-	"WARN":  color.New(color.FgYellow).SprintFunc(),
-	"ERROR": color.New(color.FgRed).SprintFunc(),
-	"FATAL": color.New(color.FgRed, color.Bold).SprintFunc(),
+	"TRACE":    color.New(color.FgHiWhite).SprintFunc(),
+	"DEBUG":    color.New(color.FgHiBlack).SprintFunc(),
+	"INFO":     color.New(color.FgGreen).SprintFunc(),
+	"NOTICE":   color.New(color.FgCyan).SprintFunc(),
+	"WARN":     color.New(color.FgYellow).SprintFunc(),
+	"ERROR":    color.New(color.FgRed).SprintFunc(),
+	"CRITICAL": color.New(color.FgHiRed, color.Bold).SprintFunc(),
+	"ALERT":    color.New(color.FgHiRed, color.Bold).SprintFunc(),
+	"FATAL":    color.New(color.FgRed, color.Bold).SprintFunc(),
 }
 
+// ANSI sequence that resets all colors and attributes
+const ansiReset = "\x1b[0m"
+
+// SGR sequences that start with a reset, such as \x1b[0m and the
+// \x1b[0;0;22m that ends a bold color with a background
+var ansiResetPattern = regexp.MustCompile(`\x1b\[0(?:;[0-9]*)*m`)
+
+// Color for context lines printed around matches
+var dimColor = color.New(color.Faint).SprintFunc()
+
 // ColorizeByLevel applies color to text based on log level
 func ColorizeByLevel(text, level string) string {
 	level = strings.ToUpper(level)
 	// Try to find color by level name
 	if colorFunc, exists := levelColors[level]; exists {
-		return colorFunc(text)
+		return colorizeNested(text, colorFunc)
 	}
 	return text
 }
 
-// FormatLog dynamically applies formatting and color to log entries
-func FormatLog(fields map[string]string, format string, fieldOrder []string, hideMissing bool) string {
-	// If --fields is specified but no --format, construct a space-separated output
-	if format == "" {
-		values := []string{}
-		for _, field := range fieldOrder {
-			if val, exists := fields[field]; exists {
-				values = append(values, val)
-			}
-		}
-		result := strings.Join(values, " ")
-
-		// Apply color based on log level
-		if level, exists := fields["level"]; exists {
-			level = strings.ToUpper(level)
-			if colorFunc, exists := levelColors[level]; exists {
-				return colorFunc(result)
-			}
-		}
-		return result
-	}
-
-	// If --format is specified, apply it dynamically
-	result := format
-	for _, field := range fieldOrder {
-		placeholder := "{" + field + "}"
-		val, exists := fields[field]
-		if !exists || val == "" {
-			if hideMissing {
-				// Remove the placeholder and any surrounding brackets
-				result = strings.ReplaceAll(result, "["+placeholder+"]", "")
-				result = strings.ReplaceAll(result, placeholder, "")
-				// Clean up any extra spaces
-				result = strings.ReplaceAll(result, "  ", " ")
-				result = strings.TrimSpace(result)
-			} else {
-				// Keep the brackets but remove the placeholder
-				result = strings.ReplaceAll(result, placeholder, "")
-			}
-		} else {
-			result = strings.ReplaceAll(result, placeholder, val)
-		}
-	}
+// Dim renders text in a faint color
+func Dim(text string) string {
+	return colorizeNested(text, dimColor)
+}
 
-	// Apply color based on log level
-	if level, exists := fields["level"]; exists {
-		level = strings.ToUpper(level)
-		if colorFunc, exists := levelColors[level]; exists {
-			return colorFunc(result)
+// colorizeNested applies a color to text and re-applies it after any reset
+// embedded in the text, so highlighted values don't cut the line color short
+func colorizeNested(text string, colorFunc func(a ...any) string) string {
+	parts := ansiResetPattern.Split(text, -1)
+	for i, part := range parts {
+		if part != "" {
+			parts[i] = colorFunc(part)
 		}
 	}
-	return result
+	return strings.Join(parts, "")
 }
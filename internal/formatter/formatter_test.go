@@ -3,87 +3,80 @@ package formatter
 import (
 	"strings"
 	"testing"
+
+	"github.com/fatih/color"
 )
 
-func TestFormatLog(t *testing.T) {
+func TestColorizeByLevel(t *testing.T) {
+	oldNoColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = oldNoColor }()
+
+	red := color.New(color.FgRed).SprintFunc()
+	highlight := color.New(color.BgYellow).SprintFunc()
+	// Attributes of the --grep and gap highlights, whose resets are not a
+	// plain \x1b[0m
+	grep := color.New(color.FgHiWhite, color.BgRed, color.Bold).SprintFunc()
+	gap := color.New(color.FgHiMagenta, color.Bold).SprintFunc()
+
 	tests := []struct {
-		name        string
-		fields      map[string]string
-		format      string
-		fieldOrder  []string
-		hideMissing bool
-		want        string
+		name  string
+		text  string
+		level string
+		want  string
 	}{
 		{
-			name: "Basic formatting",
-			fields: map[string]string{
-				"timestamp": "2024-03-20",
-				"level":     "INFO",
-				"message":   "test message",
-			},
-			format:      "{timestamp} [{level}] {message}",
-			fieldOrder:  []string{"timestamp", "level", "message"},
-			hideMissing: false,
-			want:        "2024-03-20 [INFO] test message",
+			name:  "Known level",
+			text:  "failed",
+			level: "error",
+			want:  red("failed"),
 		},
 		{
-			name: "No format string",
-			fields: map[string]string{
-				"timestamp": "2024-03-20",
-				"level":     "INFO",
-				"message":   "test message",
-			},
-			format:      "",
-			fieldOrder:  []string{"timestamp", "level", "message"},
-			hideMissing: false,
-			want:        "2024-03-20 INFO test message",
+			name:  "Unknown level",
+			text:  "hello",
+			level: "verbose",
+			want:  "hello",
 		},
 		{
-			name: "Missing fields not hidden",
-			fields: map[string]string{
-				"timestamp": "2024-03-20",
-				"message":   "test message",
-			},
-			format:      "{timestamp} [{level}] {message}",
-			fieldOrder:  []string{"timestamp", "level", "message"},
-			hideMissing: false,
-			want:        "2024-03-20 [] test message",
+			name:  "Embedded reset keeps level color",
+			text:  "a " + highlight("b") + " c",
+			level: "ERROR",
+			want:  red("a \x1b[43mb") + red(" c"),
 		},
 		{
-			name: "Missing fields hidden",
-			fields: map[string]string{
-				"timestamp": "2024-03-20",
-				"message":   "test message",
-			},
-			format:      "{timestamp} [{level}] {message}",
-			fieldOrder:  []string{"timestamp", "level", "message"},
-			hideMissing: true,
-			want:        "2024-03-20 test message",
+			name:  "Bold highlight reset keeps level color",
+			text:  "x " + grep("boom") + " tail",
+			level: "ERROR",
+			want:  red("x \x1b[97;41;1mboom") + red(" tail"),
 		},
 		{
-			name: "Different log level colors",
-			fields: map[string]string{
-				"level": "ERROR",
-			},
-			format:      "{level}",
-			fieldOrder:  []string{"level"},
-			hideMissing: false,
-			want:        "ERROR", // Note: actual output will include color codes
+			name:  "Bold color reset keeps level color",
+			text:  gap("+00:04.000") + " slow",
+			level: "ERROR",
+			want:  red("\x1b[95;1m+00:04.000") + red(" slow"),
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := FormatLog(tt.fields, tt.format, tt.fieldOrder, tt.hideMissing)
-			// Remove ANSI color codes for comparison
-			got = stripANSI(got)
-			if !strings.Contains(got, tt.want) {
-				t.Errorf("FormatLog() = %v, want %v", got, tt.want)
+			if got := ColorizeByLevel(tt.text, tt.level); got != tt.want {
+				t.Errorf("ColorizeByLevel() = %q, want %q", got, tt.want)
 			}
 		})
 	}
 }
 
+func TestDim(t *testing.T) {
+	oldNoColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = oldNoColor }()
+
+	faint := color.New(color.Faint).SprintFunc()
+	if got, want := Dim("context line"), faint("context line"); got != want {
+		t.Errorf("Dim() = %q, want %q", got, want)
+	}
+}
+
 // stripANSI removes ANSI color codes from a string
 func stripANSI(str string) string {
 	// ANSI color code replacer
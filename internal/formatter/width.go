@@ -0,0 +1,186 @@
+package formatter
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Ellipsis marks text shortened by TruncateWidth
+const Ellipsis = "…"
+
+// Code points displayed two columns wide: East Asian wide and fullwidth
+// characters, and emoji shown in emoji presentation by default
+var wideRanges = [][2]rune{
+	{0x1100, 0x115F}, {0x231A, 0x231B}, {0x2329, 0x232A}, {0x23E9, 0x23EC},
+	{0x23F0, 0x23F0}, {0x23F3, 0x23F3}, {0x25FD, 0x25FE}, {0x2614, 0x2615},
+	{0x2648, 0x2653}, {0x267F, 0x267F}, {0x2693, 0x2693}, {0x26A1, 0x26A1},
+	{0x26AA, 0x26AB}, {0x26BD, 0x26BE}, {0x26C4, 0x26C5}, {0x26CE, 0x26CE},
+	{0x26D4, 0x26D4}, {0x26EA, 0x26EA}, {0x26F2, 0x26F3}, {0x26F5, 0x26F5},
+	{0x26FA, 0x26FA}, {0x26FD, 0x26FD}, {0x2705, 0x2705}, {0x270A, 0x270B},
+	{0x2728, 0x2728}, {0x274C, 0x274C}, {0x274E, 0x274E}, {0x2753, 0x2755},
+	{0x2757, 0x2757}, {0x2795, 0x2797}, {0x27B0, 0x27B0}, {0x27BF, 0x27BF},
+	{0x2B1B, 0x2B1C}, {0x2B50, 0x2B50}, {0x2B55, 0x2B55}, {0x2E80, 0x303E},
+	{0x3041, 0x33FF}, {0x3400, 0x4DBF}, {0x4E00, 0x9FFF}, {0xA000, 0xA4CF},
+	{0xA960, 0xA97F}, {0xAC00, 0xD7A3}, {0xF900, 0xFAFF}, {0xFE10, 0xFE19},
+	{0xFE30, 0xFE6F}, {0xFF00, 0xFF60}, {0xFFE0, 0xFFE6}, {0x16FE0, 0x16FE4},
+	{0x17000, 0x18CFF}, {0x1B000, 0x1B2FF}, {0x1F004, 0x1F004}, {0x1F0CF, 0x1F0CF},
+	{0x1F18E, 0x1F18E}, {0x1F191, 0x1F19A}, {0x1F1E6, 0x1F1FF}, {0x1F200, 0x1F2FF},
+	{0x1F300, 0x1F320}, {0x1F32D, 0x1F335}, {0x1F337, 0x1F37C}, {0x1F37E, 0x1F393},
+	{0x1F3A0, 0x1F3CA}, {0x1F3CF, 0x1F3D3}, {0x1F3E0, 0x1F3F0}, {0x1F3F4, 0x1F3F4},
+	{0x1F3F8, 0x1F43E}, {0x1F440, 0x1F440}, {0x1F442, 0x1F4FC}, {0x1F4FF, 0x1F53D},
+	{0x1F54B, 0x1F54E}, {0x1F550, 0x1F567}, {0x1F57A, 0x1F57A}, {0x1F595, 0x1F596},
+	{0x1F5A4, 0x1F5A4}, {0x1F5FB, 0x1F64F}, {0x1F680, 0x1F6C5}, {0x1F6CC, 0x1F6CC},
+	{0x1F6D0, 0x1F6D2}, {0x1F6D5, 0x1F6D7}, {0x1F6DC, 0x1F6DF}, {0x1F6EB, 0x1F6EC},
+	{0x1F6F4, 0x1F6FC}, {0x1F7E0, 0x1F7EB}, {0x1F7F0, 0x1F7F0}, {0x1F90C, 0x1F93A},
+	{0x1F93C, 0x1F945}, {0x1F947, 0x1F9FF}, {0x1FA70, 0x1FAFF}, {0x20000, 0x2FFFD},
+	{0x30000, 0x3FFFD},
+}
+
+const (
+	zeroWidthJoiner = 0x200D
+	emojiSelector   = 0xFE0F
+	regionalA       = 0x1F1E6
+	regionalZ       = 0x1F1FF
+)
+
+// runeWidth returns the number of terminal columns a single rune occupies
+func runeWidth(r rune) int {
+	switch {
+	case r < 0x20 || (r >= 0x7F && r < 0xA0):
+		return 0
+	case r < 0x300:
+		return 1
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) || (r >= 0x1F3FB && r <= 0x1F3FF):
+		// Combining marks, format characters (ZWJ, variation selectors) and skin tones
+		return 0
+	}
+	i := sort.Search(len(wideRanges), func(i int) bool { return wideRanges[i][1] >= r })
+	if i < len(wideRanges) && wideRanges[i][0] <= r {
+		return 2
+	}
+	return 1
+}
+
+// ansiSequenceLen returns the length of the ANSI escape sequence at the
+// start of s, or 0 if s does not start with one
+func ansiSequenceLen(s string) int {
+	if len(s) < 2 || s[0] != '\x1b' || s[1] != '[' {
+		return 0
+	}
+	for i := 2; i < len(s); i++ {
+		if s[i] >= 0x40 && s[i] <= 0x7E {
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// graphemeWidths walks s and calls fn with the byte offset, size and width
+// of each rune or ANSI sequence. Widths add up per grapheme: sequences and
+// runes joined to the previous one count zero.
+func graphemeWidths(s string, fn func(offset, size, width int) bool) {
+	joined := false
+	pendingFlag := false
+	prevWidth := 0
+	for i := 0; i < len(s); {
+		if n := ansiSequenceLen(s[i:]); n > 0 {
+			if !fn(i, n, 0) {
+				return
+			}
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		width := runeWidth(r)
+		switch {
+		case joined:
+			// Emoji ZWJ sequences render as a single glyph
+			width = 0
+		case r == emojiSelector && prevWidth == 1:
+			// VS16 turns a text symbol such as ❤ into a two-column emoji
+			width = 1
+		case r >= regionalA && r <= regionalZ:
+			// A pair of regional indicators renders as one flag
+			if pendingFlag {
+				width = 0
+			}
+			pendingFlag = !pendingFlag
+		default:
+			pendingFlag = false
+		}
+		joined = r == zeroWidthJoiner
+		prevWidth = width
+		if !fn(i, size, width) {
+			return
+		}
+		i += size
+	}
+}
+
+// DisplayWidth returns the number of terminal columns text occupies,
+// counting wide CJK characters and emoji as two and ignoring ANSI sequences
+func DisplayWidth(text string) int {
+	total := 0
+	graphemeWidths(text, func(_, _, width int) bool {
+		total += width
+		return true
+	})
+	return total
+}
+
+// TruncateWidth shortens text to at most width columns, ending it with an
+// ellipsis when anything was cut. ANSI sequences are kept and colors are
+// reset after a cut so they do not leak into the rest of the line.
+func TruncateWidth(text string, width int) string {
+	if DisplayWidth(text) <= width {
+		return text
+	}
+	if width <= 0 {
+		return ""
+	}
+
+	limit := width - DisplayWidth(Ellipsis)
+	var b strings.Builder
+	used := 0
+	colored := false
+	graphemeWidths(text, func(offset, size, w int) bool {
+		if w == 0 {
+			if text[offset] == '\x1b' {
+				colored = true
+			}
+			b.WriteString(text[offset : offset+size])
+			return true
+		}
+		if used+w > limit {
+			return false
+		}
+		used += w
+		b.WriteString(text[offset : offset+size])
+		return true
+	})
+	b.WriteString(Ellipsis)
+	if colored {
+		b.WriteString(ansiReset)
+	}
+	return b.String()
+}
+
+// PadWidth pads text with spaces to width columns. Align is '<' (left),
+// '>' (right) or '^' (center); text already that wide is returned unchanged.
+func PadWidth(text string, width int, align byte) string {
+	gap := width - DisplayWidth(text)
+	if gap <= 0 {
+		return text
+	}
+	switch align {
+	case '>':
+		return strings.Repeat(" ", gap) + text
+	case '^':
+		left := gap / 2
+		return strings.Repeat(" ", left) + text + strings.Repeat(" ", gap-left)
+	default:
+		return text + strings.Repeat(" ", gap)
+	}
+}
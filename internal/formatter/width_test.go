@@ -0,0 +1,83 @@
+package formatter
+
+import "testing"
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{name: "ASCII", text: "INFO", want: 4},
+		{name: "Empty", text: "", want: 0},
+		{name: "CJK", text: "日本語", want: 6},
+		{name: "Fullwidth and Hangul", text: "Ａ한", want: 4},
+		{name: "Mixed", text: "ok 完了", want: 7},
+		{name: "Emoji", text: "🔥❓", want: 4},
+		{name: "Combining mark", text: "é", want: 1},
+		{name: "Text symbol", text: "❤", want: 1},
+		{name: "Emoji variation selector", text: "❤️", want: 2},
+		{name: "ZWJ sequence", text: "👩‍💻", want: 2},
+		{name: "Skin tone", text: "👍🏽", want: 2},
+		{name: "Flag", text: "🇯🇵", want: 2},
+		{name: "ANSI sequences", text: "\x1b[31mERROR\x1b[0m", want: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DisplayWidth(tt.text); got != tt.want {
+				t.Errorf("DisplayWidth(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateWidth(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		width int
+		want  string
+	}{
+		{name: "Fits", text: "hello", width: 5, want: "hello"},
+		{name: "Cut with ellipsis", text: "hello world", width: 8, want: "hello w…"},
+		{name: "CJK", text: "日本語のログ", width: 7, want: "日本語…"},
+		{name: "CJK does not split a wide character", text: "日本語のログ", width: 6, want: "日本…"},
+		{name: "Emoji", text: "🔥🔥🔥", width: 4, want: "🔥…"},
+		{name: "Zero width", text: "hello", width: 0, want: ""},
+		{name: "ANSI color is reset", text: "\x1b[43mhighlight\x1b[0m", width: 5, want: "\x1b[43mhigh…\x1b[0m"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TruncateWidth(tt.text, tt.width); got != tt.want {
+				t.Errorf("TruncateWidth(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPadWidth(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		width int
+		align byte
+		want  string
+	}{
+		{name: "Left", text: "INFO", width: 6, align: '<', want: "INFO  "},
+		{name: "Right", text: "200", width: 5, align: '>', want: "  200"},
+		{name: "Center", text: "ok", width: 5, align: '^', want: " ok  "},
+		{name: "CJK", text: "完了", width: 6, align: '<', want: "完了  "},
+		{name: "Already wide", text: "WARNING", width: 4, align: '<', want: "WARNING"},
+		{name: "ANSI ignored", text: "\x1b[31mok\x1b[0m", width: 4, align: '>', want: "  \x1b[31mok\x1b[0m"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PadWidth(tt.text, tt.width, tt.align); got != tt.want {
+				t.Errorf("PadWidth(%q, %d, %q) = %q, want %q", tt.text, tt.width, tt.align, got, tt.want)
+			}
+		})
+	}
+}
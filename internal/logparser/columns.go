@@ -0,0 +1,155 @@
+package logparser
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/techarm/jclog/internal/formatter"
+)
+
+// Rows that size the --fields columns before their widths are fixed
+const columnSampleRows = 100
+
+// How long the sample rows wait for more input before they are printed,
+// so that a stream such as tail -f shows its first rows
+const columnSampleIdle = 200 * time.Millisecond
+
+// columnPrinter renders records as the aligned columns of --fields. Rows
+// are held back until the sample window has sized the columns.
+type columnPrinter struct {
+	renderer *formatRenderer
+	columns  *formatter.Columns
+	print    func(output, level string, context bool)
+
+	// mu guards the columns against the idle flush
+	mu sync.Mutex
+	// pending holds the levels and context flags of the held-back rows,
+	// and the lines queued between them
+	pending []columnRow
+	idle    *time.Timer
+}
+
+// columnRow is how a held-back row is printed
+type columnRow struct {
+	level   string
+	context bool
+	// line is printed as is in place of a row, as for context separators
+	// and Invalid JSON lines
+	line        string
+	passthrough bool
+}
+
+// parseFields parses --fields entries, each a field name or path with
+// optional modifiers and format spec, into a template with one
+// placeholder per column
+func parseFields(fields []string) (*formatTemplate, error) {
+	segments := make([]segment, 0, len(fields))
+	for _, field := range fields {
+		ph, err := parsePlaceholder(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid field %q: %v", field, err)
+		}
+		segments = append(segments, segment{kind: segmentField, text: field, field: ph})
+	}
+	return newFormatTemplate(segments), nil
+}
+
+// ValidateFields checks the entries of --fields
+func ValidateFields(fields []string) error {
+	_, err := parseFields(fields)
+	return err
+}
+
+// newColumnPrinter creates a printer for a template from parseFields that
+// hands each line to print with the level to color it by; the header names
+// the fields without their modifiers and specs
+func newColumnPrinter(tmpl *formatTemplate, opts Options, print func(output, level string, context bool)) *columnPrinter {
+	names := make([]string, len(tmpl.code))
+	for i, in := range tmpl.code {
+		names[i] = in.field.field
+	}
+	return &columnPrinter{
+		renderer: newFormatRenderer(tmpl, opts),
+		columns:  formatter.NewColumns(names, columnSampleRows),
+		print:    print,
+	}
+}
+
+// add renders a record as a row. Missing fields leave their cell empty.
+// Rows of the sample window are printed with the header once it is full,
+// the input pauses for columnSampleIdle, or flush is called.
+func (p *columnPrinter) add(rec *record, context bool) {
+	code := p.renderer.tmpl.code
+	cells := make([]string, len(code))
+	for i := range code {
+		if value, ok := p.renderer.value(rec, &code[i]); ok {
+			cells[i] = value
+		}
+	}
+	var level string
+	if p.renderer.tmpl.hasLevel {
+		level = rec.level(p.renderer.opts.Levels).String()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pending = append(p.pending, columnRow{level: level, context: context})
+	p.printRows(p.columns.Row(cells))
+	switch {
+	case len(p.pending) == 0:
+	case p.idle == nil:
+		p.idle = time.AfterFunc(columnSampleIdle, p.flush)
+	default:
+		p.idle.Reset(columnSampleIdle)
+	}
+}
+
+// separate prints the separator between context groups, after the rows
+// held back before it
+func (p *columnPrinter) separate() {
+	p.passthrough(contextSeparator)
+}
+
+// passthrough prints a line that is not a row, after the rows held back
+// before it, so lines keep the order of the input
+func (p *columnPrinter) passthrough(line string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.pending) == 0 {
+		p.print(line, "", false)
+		return
+	}
+	p.pending = append(p.pending, columnRow{line: line, passthrough: true})
+}
+
+// flush prints the held-back rows with the header
+func (p *columnPrinter) flush() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.idle != nil {
+		p.idle.Stop()
+	}
+	p.printRows(p.columns.Flush())
+}
+
+// printRows prints the rows the columns released, which are the pending
+// ones, after the header row if there is one, with the lines queued
+// between them
+func (p *columnPrinter) printRows(header string, rows []string) {
+	if len(rows) == 0 {
+		return
+	}
+	if header != "" {
+		p.print(header, "", false)
+	}
+	for _, pending := range p.pending {
+		if pending.passthrough {
+			p.print(pending.line, "", false)
+			continue
+		}
+		p.print(rows[0], pending.level, pending.context)
+		rows = rows[1:]
+	}
+	p.pending = p.pending[:0]
+}
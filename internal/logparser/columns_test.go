@@ -0,0 +1,171 @@
+package logparser
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProcessLogFields(t *testing.T) {
+	input := `{"time": "10:00:01", "level": "INFO", "msg": "started", "user": "alice"}
+{"time": "10:00:02", "level": "WARN", "msg": "slow request"}
+{"time": "10:00:03", "lvl": "ERROR", "msg": "failed", "user": "bob", "code": 500}`
+
+	tests := []struct {
+		name    string
+		input   string
+		fields  []string
+		filter  string
+		jq      string
+		context int
+		want    []string
+	}{
+		{
+			name:   "Header and aligned rows",
+			fields: []string{"time", "level", "user", "message"},
+			want: []string{
+				"time      level  user   message",
+				"10:00:01  INFO   alice  started",
+				"10:00:02  WARN          slow request",
+				"10:00:03  ERROR  bob    failed",
+			},
+		},
+		{
+			name:   "Widths fit every sample row",
+			fields: []string{"msg", "time"},
+			want: []string{
+				"msg           time",
+				"started       10:00:01",
+				"slow request  10:00:02",
+				"failed        10:00:03",
+			},
+		},
+		{
+			name:   "Modifiers and rest",
+			fields: []string{"level|lower", "rest"},
+			want: []string{
+				"level  rest",
+				"info   time=10:00:01 msg=started user=alice",
+				"warn   time=10:00:02 msg=\"slow request\"",
+				"error  time=10:00:03 msg=failed user=bob code=500",
+			},
+		},
+		{
+			name: "Context groups keep their separator",
+			input: `{"level": "ERROR", "msg": "a"}
+{"level": "INFO", "msg": "b"}
+{"level": "INFO", "msg": "c"}
+{"level": "INFO", "msg": "d"}
+{"level": "ERROR", "msg": "e"}`,
+			fields:  []string{"level", "msg"},
+			filter:  "level=ERROR",
+			context: 1,
+			want: []string{
+				"level  msg",
+				"ERROR  a",
+				"INFO   b",
+				"--",
+				"INFO   d",
+				"ERROR  e",
+			},
+		},
+		{
+			name: "Invalid JSON keeps its place",
+			input: `{"level": "INFO", "msg": "a"}
+not json
+{"level": "ERROR", "msg": "b"}`,
+			fields: []string{"level", "msg"},
+			want: []string{
+				"level  msg",
+				"INFO   a",
+				"Invalid JSON: not json",
+				"ERROR  b",
+			},
+		},
+		{
+			name: "jq errors keep their place",
+			input: `{"level": "INFO", "msg": "a", "n": "1"}
+{"level": "WARN", "msg": "b", "n": "x"}
+{"level": "ERROR", "msg": "c", "n": "3"}`,
+			fields: []string{"level", "msg"},
+			jq:     ".n | tonumber | . > 0",
+			want: []string{
+				"level  msg",
+				"INFO   a",
+				`jq error: tonumber cannot be applied to "x": invalid number`,
+				"ERROR  c",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{Fields: tt.fields, BeforeContext: tt.context, AfterContext: tt.context}
+			if tt.filter != "" {
+				opts.Filters = []Filter{mustParseFilter(t, tt.filter)}
+			}
+			if tt.jq != "" {
+				jq, err := ParseJQ(tt.jq)
+				if err != nil {
+					t.Fatal(err)
+				}
+				opts.JQ = jq
+			}
+			text := input
+			if tt.input != "" {
+				text = tt.input
+			}
+			out := captureStdout(t, func() {
+				ProcessLog(bufio.NewScanner(strings.NewReader(text)), opts)
+			})
+			got := strings.Split(stripANSI(strings.TrimSuffix(out, "\n")), "\n")
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("output:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestColumnPrinterIdleFlush(t *testing.T) {
+	tmpl, err := parseFields([]string{"user", "message"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := make(chan string, 10)
+	p := newColumnPrinter(tmpl, Options{}, func(output, level string, context bool) {
+		lines <- stripANSI(output)
+	})
+
+	p.add(newRecord(map[string]any{"user": "al", "msg": "first"}, 1), false)
+	p.add(newRecord(map[string]any{"user": "carol", "msg": "second"}, 1), false)
+	for _, want := range []string{"user   message", "al     first", "carol  second"} {
+		select {
+		case got := <-lines:
+			if got != want {
+				t.Errorf("line = %q, want %q", got, want)
+			}
+		case <-time.After(10 * columnSampleIdle):
+			t.Fatalf("sample rows not printed after the input paused, want %q", want)
+		}
+	}
+
+	// Later rows keep the settled widths
+	p.add(newRecord(map[string]any{"user": "mallory", "msg": "third"}, 1), false)
+	if got, want := <-lines, "mall…  third"; got != want {
+		t.Errorf("line = %q, want %q", got, want)
+	}
+	p.flush()
+	if len(lines) > 0 {
+		t.Errorf("flush() printed %q again", <-lines)
+	}
+}
+
+func TestValidateFields(t *testing.T) {
+	if err := ValidateFields([]string{"time", "http.status:>3", "msg|trunc:20"}); err != nil {
+		t.Errorf("ValidateFields() error = %v", err)
+	}
+	if err := ValidateFields([]string{"level|shout"}); err == nil {
+		t.Error("ValidateFields() accepted an unknown modifier")
+	}
+}
@@ -0,0 +1,129 @@
+package logparser
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+)
+
+// Maximum number of correlation keys tracked for context
+const maxContextKeys = 1024
+
+// Separator printed between non-adjacent groups of output, like GNU grep
+var contextSeparator = color.New(color.FgCyan).Sprint("--")
+
+// contextStream tracks the records sharing one correlation key
+type contextStream struct {
+	// position of the latest record within the stream
+	seq int
+	// recent non-matching records, at most `before`
+	buffer []contextEntry
+}
+
+type contextEntry struct {
+	seq int
+	rec *record
+}
+
+// contextPrinter prints matching records together with the records around
+// them that were filtered out, grep -A/-B/-C style. When a correlation field
+// is set, records are grouped by its value and only records sharing the
+// match's value are used as context.
+type contextPrinter struct {
+	before, after int
+	field         string
+	print         func(rec *record, context bool)
+	// separate prints the separator between groups
+	separate func()
+
+	streams map[string]*contextStream
+	keys    []string
+
+	afterLeft int
+	afterKey  string
+	lastKey   string
+	lastSeq   int
+}
+
+// printSeparator prints the separator between groups
+func printSeparator() {
+	fmt.Println(contextSeparator)
+}
+
+func newContextPrinter(before, after int, field string, print func(rec *record, context bool)) *contextPrinter {
+	return &contextPrinter{
+		before:   before,
+		after:    after,
+		field:    field,
+		print:    print,
+		separate: printSeparator,
+		streams:  make(map[string]*contextStream),
+		lastSeq:  -1,
+	}
+}
+
+// add handles the next record of the input
+func (p *contextPrinter) add(rec *record, matched bool) {
+	key := p.key(rec)
+	stream := p.stream(key)
+	stream.seq++
+
+	if matched {
+		for _, e := range stream.buffer {
+			p.emit(key, e.seq, e.rec, true)
+		}
+		stream.buffer = stream.buffer[:0]
+		p.emit(key, stream.seq, rec, false)
+		p.afterLeft = p.after
+		p.afterKey = key
+		return
+	}
+
+	if p.afterLeft > 0 && key == p.afterKey {
+		p.emit(key, stream.seq, rec, true)
+		p.afterLeft--
+		return
+	}
+
+	if p.before > 0 {
+		if len(stream.buffer) == p.before {
+			copy(stream.buffer, stream.buffer[1:])
+			stream.buffer = stream.buffer[:len(stream.buffer)-1]
+		}
+		stream.buffer = append(stream.buffer, contextEntry{stream.seq, rec})
+	}
+}
+
+// stream returns the stream for a correlation key, evicting the oldest
+// key when too many are tracked
+func (p *contextPrinter) stream(key string) *contextStream {
+	if stream, exists := p.streams[key]; exists {
+		return stream
+	}
+	p.keys = append(p.keys, key)
+	if len(p.keys) > maxContextKeys {
+		delete(p.streams, p.keys[0])
+		p.keys = p.keys[1:]
+	}
+	stream := &contextStream{}
+	p.streams[key] = stream
+	return stream
+}
+
+func (p *contextPrinter) emit(key string, seq int, rec *record, context bool) {
+	if (p.before > 0 || p.after > 0) && p.lastSeq >= 0 && (key != p.lastKey || seq != p.lastSeq+1) {
+		p.separate()
+	}
+	p.lastKey = key
+	p.lastSeq = seq
+	p.print(rec, context)
+}
+
+// key returns the record's correlation value, or "" when context is not correlated
+func (p *contextPrinter) key(rec *record) string {
+	if p.field == "" {
+		return ""
+	}
+	v, _ := rec.lookupString(p.field)
+	return v
+}
@@ -0,0 +1,121 @@
+package logparser
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestContextPrinter(t *testing.T) {
+	input := []map[string]any{
+		{"level": "INFO", "msg": "1", "rid": "a"},
+		{"level": "INFO", "msg": "2", "rid": "b"},
+		{"level": "INFO", "msg": "3", "rid": "a"},
+		{"level": "ERROR", "msg": "4", "rid": "a"},
+		{"level": "INFO", "msg": "5", "rid": "a"},
+		{"level": "INFO", "msg": "6", "rid": "b"},
+		{"level": "INFO", "msg": "7", "rid": "a"},
+		{"level": "INFO", "msg": "8", "rid": "a"},
+		{"level": "ERROR", "msg": "9", "rid": "b"},
+	}
+
+	tests := []struct {
+		name   string
+		before int
+		after  int
+		field  string
+		want   string
+	}{
+		{
+			name: "No context",
+			want: "4 9",
+		},
+		{
+			name:   "Before context",
+			before: 2,
+			want:   "~2 ~3 4 -- ~7 ~8 9",
+		},
+		{
+			name:  "After context",
+			after: 1,
+			want:  "4 ~5 -- 9",
+		},
+		{
+			name:   "Adjacent groups are merged",
+			before: 3,
+			after:  3,
+			want:   "~1 ~2 ~3 4 ~5 ~6 ~7 ~8 9",
+		},
+		{
+			name:   "Correlated context",
+			before: 2,
+			after:  2,
+			field:  "rid",
+			want:   "~1 ~3 4 ~5 ~7 -- ~2 ~6 9",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := captureStdout(t, func() {
+				p := newContextPrinter(tt.before, tt.after, tt.field, func(rec *record, context bool) {
+					msg, _ := rec.lookupString("msg")
+					if context {
+						msg = "~" + msg
+					}
+					fmt.Println(msg)
+				})
+				for _, data := range input {
+					rec := newRecord(data, 2)
+					level, _ := rec.lookupString("level")
+					p.add(rec, level == "ERROR")
+				}
+			})
+			got := strings.Join(strings.Fields(stripANSI(out)), " ")
+			if got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// captureStdout returns everything written to stdout while fn runs
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	old := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+
+	outC := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+		outC <- buf.String()
+	}()
+
+	fn()
+	w.Close()
+	os.Stdout = old
+	return <-outC
+}
+
+// stripANSI removes ANSI escape sequences from a string
+func stripANSI(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\x1b' {
+			for i < len(s) && s[i] != 'm' {
+				i++
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,198 @@
+package logparser
+
+import (
+	"cmp"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Supported filter operators, two-character operators first so that
+// ">=" is not mistaken for ">"
+var filterOperators = []string{"=~", "!=", ">=", "<=", "=", ">", "<"}
+
+// Filter represents a single field condition such as "level=ERROR" or "duration_ms>1000".
+// The "=" and "!=" operators accept a comma-separated set of values, e.g. "level=ERROR,WARN".
+type Filter struct {
+	Field    string
+	Operator string
+	Value    string
+	values   []string
+	pattern  *regexp.Regexp
+	// byLevel orders values by severity with levels, for level fields
+	byLevel bool
+	levels  *Levels
+	// time reads timestamps the way the records' timestamps are read
+	time TimeSettings
+}
+
+// ParseFilter parses a "field<op>value" expression into a Filter
+func ParseFilter(expr string) (Filter, error) {
+	for i := 1; i < len(expr); i++ {
+		for _, op := range filterOperators {
+			if !strings.HasPrefix(expr[i:], op) {
+				continue
+			}
+			f := Filter{
+				Field:    strings.TrimSpace(expr[:i]),
+				Operator: op,
+				Value:    expr[i+len(op):],
+			}
+			if f.Field == "" {
+				return Filter{}, fmt.Errorf("invalid filter %q: missing field name", expr)
+			}
+			switch op {
+			case "=", "!=":
+				f.values = strings.Split(f.Value, ",")
+			case "=~":
+				re, err := regexp.Compile(f.Value)
+				if err != nil {
+					return Filter{}, fmt.Errorf("invalid filter %q: %v", expr, err)
+				}
+				f.pattern = re
+			}
+			return f, nil
+		}
+	}
+	return Filter{}, fmt.Errorf("invalid filter %q: expected field<op>value with one of %s", expr, strings.Join(filterOperators, " "))
+}
+
+// prepareFilters readies filters for ProcessLog: level filters match every
+// spelling of their levels, and timestamps are read with ts
+func prepareFilters(filters []Filter, levels *Levels, ts TimeSettings) []Filter {
+	if len(filters) == 0 {
+		return filters
+	}
+	prepared := make([]Filter, len(filters))
+	for i, f := range filters {
+		f = f.withLevels(levels)
+		f.time = ts
+		prepared[i] = f
+	}
+	return prepared
+}
+
+// String returns the filter in its "field<op>value" form
+func (f Filter) String() string {
+	return f.Field + f.Operator + f.Value
+}
+
+// Match reports whether the given field value satisfies the filter.
+// Missing fields only satisfy the "!=" operator.
+func (f Filter) Match(value string, exists bool) bool {
+	if !exists {
+		return f.Operator == "!="
+	}
+
+	switch f.Operator {
+	case "=":
+		return f.matchAny(value)
+	case "!=":
+		return !f.matchAny(value)
+	case ">":
+		return f.compare(value) > 0
+	case ">=":
+		return f.compare(value) >= 0
+	case "<":
+		return f.compare(value) < 0
+	case "<=":
+		return f.compare(value) <= 0
+	case "=~":
+		return f.pattern != nil && f.pattern.MatchString(value)
+	}
+	return false
+}
+
+// compare orders a field value against the filter's value
+func (f Filter) compare(value string) int {
+	if f.byLevel {
+		return compareLevels(value, f.Value, f.levels)
+	}
+	return compareValues(value, f.Value, f.time)
+}
+
+// MatchAny matches the filter against several values of a field, as
+// produced by wildcard paths like errors[*].code. A value set matches if
+// any value satisfies the filter, or if all do for the "!=" operator.
+func (f Filter) MatchAny(values []string, exists bool) bool {
+	if !exists {
+		return f.Match("", false)
+	}
+	if f.Operator == "!=" {
+		for _, value := range values {
+			if !f.Match(value, true) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, value := range values {
+		if f.Match(value, true) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAny reports whether the value equals any value in the filter's set
+func (f Filter) matchAny(value string) bool {
+	for _, expected := range f.values {
+		if compareValues(value, expected, f.time) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// compareValues compares two values as numbers if both are numeric, as times
+// if both read as timestamps with ts, and as plain strings otherwise. A
+// number compared with a timestamp is read as an epoch value.
+func compareValues(actual, expected string, ts TimeSettings) int {
+	// Compare integers exactly so large IDs differing in the last digits stay distinct
+	if c, ok := compareIntegers(actual, expected); ok {
+		return c
+	}
+	if a, err := strconv.ParseFloat(actual, 64); err == nil {
+		if b, err := strconv.ParseFloat(expected, 64); err == nil {
+			return cmp.Compare(a, b)
+		}
+	}
+	if a, ok := ts.parse(actual); ok {
+		if b, ok := ts.parse(expected); ok {
+			return a.Compare(b)
+		}
+	}
+	return strings.Compare(actual, expected)
+}
+
+// compareIntegers compares two integer literals exactly, including those
+// beyond int64 such as uint64 trace IDs
+func compareIntegers(a, b string) (int, bool) {
+	if x, err := strconv.ParseInt(a, 10, 64); err == nil {
+		if y, err := strconv.ParseInt(b, 10, 64); err == nil {
+			return cmp.Compare(x, y), true
+		}
+	}
+	if !isIntegerLiteral(a) || !isIntegerLiteral(b) {
+		return 0, false
+	}
+	x, _ := new(big.Int).SetString(a, 10)
+	y, _ := new(big.Int).SetString(b, 10)
+	return x.Cmp(y), true
+}
+
+// isIntegerLiteral reports whether s is a decimal integer with an optional sign
+func isIntegerLiteral(s string) bool {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
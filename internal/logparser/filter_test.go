@@ -0,0 +1,132 @@
+package logparser
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func mustParseFilter(t *testing.T, expr string) Filter {
+	t.Helper()
+	f, err := ParseFilter(expr)
+	if err != nil {
+		t.Fatalf("ParseFilter(%q) error = %v", expr, err)
+	}
+	return f
+}
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		expr     string
+		field    string
+		operator string
+		value    string
+		wantErr  bool
+	}{
+		{name: "Equality", expr: "level=ERROR", field: "level", operator: "=", value: "ERROR"},
+		{name: "Inequality", expr: "level!=DEBUG", field: "level", operator: "!=", value: "DEBUG"},
+		{name: "Greater than", expr: "duration_ms>1000", field: "duration_ms", operator: ">", value: "1000"},
+		{name: "Greater or equal", expr: "cpu_usage>=80", field: "cpu_usage", operator: ">=", value: "80"},
+		{name: "Less than", expr: "http_code<500", field: "http_code", operator: "<", value: "500"},
+		{name: "Less or equal", expr: "retries<=3", field: "retries", operator: "<=", value: "3"},
+		{name: "Regex", expr: "msg=~timeout|refused", field: "msg", operator: "=~", value: "timeout|refused"},
+		{name: "Value containing operator", expr: "query=a=b", field: "query", operator: "=", value: "a=b"},
+		{name: "Empty value", expr: "error=", field: "error", operator: "=", value: ""},
+		{name: "No operator", expr: "level", wantErr: true},
+		{name: "Missing field", expr: "=ERROR", wantErr: true},
+		{name: "Invalid regex", expr: "msg=~[", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFilter(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Field != tt.field || got.Operator != tt.operator || got.Value != tt.value {
+				t.Errorf("ParseFilter() = %q %q %q, want %q %q %q", got.Field, got.Operator, got.Value, tt.field, tt.operator, tt.value)
+			}
+		})
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		value  string
+		exists bool
+		want   bool
+	}{
+		{name: "String equal", filter: "level=ERROR", value: "ERROR", exists: true, want: true},
+		{name: "String not equal", filter: "level=ERROR", value: "INFO", exists: true, want: false},
+		{name: "Numeric equal", filter: "http_code=200", value: "200.0", exists: true, want: true},
+		{name: "Large integer IDs differ", filter: "user_id=1234567890123456789", value: "1234567890123456788", exists: true, want: false},
+		{name: "Integers beyond int64 differ", filter: "id=12345678901234567891", value: "12345678901234567890", exists: true, want: false},
+		{name: "Integers beyond int64 equal", filter: "id=12345678901234567891", value: "12345678901234567891", exists: true, want: true},
+		{name: "Integers beyond int64 ordered", filter: "trace_id>18446744073709551614", value: "18446744073709551615", exists: true, want: true},
+		{name: "Negative integers beyond int64", filter: "n<-9223372036854775809", value: "-9223372036854775810", exists: true, want: true},
+		{name: "Numeric greater", filter: "duration_ms>1000", value: "1500", exists: true, want: true},
+		{name: "Numeric not lexical", filter: "duration_ms>1000", value: "999", exists: true, want: false},
+		{name: "Numeric greater or equal", filter: "cpu_usage>=80", value: "80", exists: true, want: true},
+		{name: "Numeric less", filter: "http_code<500", value: "404", exists: true, want: true},
+		{name: "Numeric less or equal", filter: "retries<=3", value: "4", exists: true, want: false},
+		{name: "Time comparison", filter: "time>2024-03-20T10:00:00Z", value: "2024-03-20T19:00:01+09:00", exists: true, want: true},
+		{name: "Time before", filter: "time<2024-03-20T10:00:00Z", value: "2024-03-20 10:00:01", exists: true, want: false},
+		{name: "Inequality", filter: "level!=DEBUG", value: "INFO", exists: true, want: true},
+		{name: "Inequality on missing field", filter: "level!=DEBUG", exists: false, want: true},
+		{name: "Regex match", filter: "msg=~timeout|refused", value: "connection refused", exists: true, want: true},
+		{name: "Regex no match", filter: "msg=~timeout|refused", value: "ok", exists: true, want: false},
+		{name: "Missing field", filter: "duration_ms>1000", exists: false, want: false},
+		{name: "Value set match", filter: "level=ERROR,WARN,FATAL", value: "WARN", exists: true, want: true},
+		{name: "Value set no match", filter: "level=ERROR,WARN,FATAL", value: "INFO", exists: true, want: false},
+		{name: "Negated value set", filter: "level!=DEBUG,TRACE", value: "TRACE", exists: true, want: false},
+		{name: "Regex keeps commas", filter: "code=~^a{1,2}$", value: "aa", exists: true, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := mustParseFilter(t, tt.filter)
+			if got := f.Match(tt.value, tt.exists); got != tt.want {
+				t.Errorf("Match(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterTimeSettings(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip("timezone data not available")
+	}
+
+	tests := []struct {
+		name   string
+		filter string
+		value  any
+		ts     TimeSettings
+		want   bool
+	}{
+		{name: "Epoch seconds after", filter: "ts>2022-03-20T08:00:00Z", value: json.Number("1647763200.123"), want: true},
+		{name: "Epoch seconds before", filter: "ts<2022-03-20T08:00:00Z", value: json.Number("1647763200.123"), want: false},
+		{name: "Epoch milliseconds", filter: "ts<2022-03-20T08:00:01Z", value: json.Number("1647763200123"), want: true},
+		{name: "Epoch unit", filter: "ts>2022-03-20T08:00:00Z", value: json.Number("1647763200123"), ts: TimeSettings{EpochUnit: time.Microsecond}, want: false},
+		{name: "Input timezone", filter: "time<2024-03-20T10:00:00Z", value: "2024-03-20 18:00:00", ts: TimeSettings{InputLocation: tokyo}, want: true},
+		{name: "Custom layout", filter: "time<2024-11-28T22:00:00Z", value: "28/Nov/2024:21:30:45 +0000", ts: TimeSettings{Layouts: NewTimeLayouts([]string{"02/Jan/2006:15:04:05 -0700"})}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filters := prepareFilters([]Filter{mustParseFilter(t, tt.filter)}, nil, tt.ts)
+			field := filters[0].Field
+			rec := newRecord(map[string]any{field: tt.value}, 2)
+			if got := matchFilters(rec, filters, nil); got != tt.want {
+				t.Errorf("matchFilters(%s) on %v = %v, want %v", tt.filter, tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,292 @@
+package logparser
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// formatTemplate is a parsed output format
+type formatTemplate struct {
+	segments []segment
+	// code is the segments compiled into an instruction list
+	code []instruction
+	// hasLevel reports whether any placeholder shows the level, which
+	// decides whether the line is colored by level
+	hasLevel bool
+	// hasRest reports whether the format shows {rest}, which needs the
+	// record's key order
+	hasRest bool
+	// used lists the fields the format names, which {rest} leaves out
+	used []string
+}
+
+type segmentKind int
+
+const (
+	segmentLiteral segmentKind = iota
+	segmentField
+	// segmentSection is emitted only when every field inside it is present,
+	// as in {?error: err={error}} or [[ user={user}]]
+	segmentSection
+	// segmentWord groups a whitespace-separated word of the format with the
+	// space before it, so --hide-missing can drop "(service={service})" whole
+	segmentWord
+)
+
+type segment struct {
+	kind     segmentKind
+	text     string      // literal text, or the placeholder source for fields
+	field    placeholder // parsed placeholder for fields
+	requires string      // field named by {?field: ...}
+	children []segment   // body of sections and words
+}
+
+// parseFormat parses a format string into literal text, placeholders and
+// optional sections. {{ and }} stand for literal braces. Output is built
+// from the parsed segments, so braces in field values are never read as
+// placeholders.
+func parseFormat(format string) (*formatTemplate, error) {
+	p := &formatParser{src: format}
+	segments, err := p.parseSegments("", 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid format %q: %v", format, err)
+	}
+	return newFormatTemplate(groupWords(segments)), nil
+}
+
+// newFormatTemplate compiles parsed segments and records which fields they use
+func newFormatTemplate(segments []segment) *formatTemplate {
+	t := &formatTemplate{segments: segments}
+	walkFields(t.segments, func(ph placeholder) {
+		switch ph.field {
+		case "level":
+			t.hasLevel = true
+		case restField:
+			t.hasRest = true
+			return
+		}
+		t.used = append(t.used, ph.field)
+	})
+	walkSections(t.segments, func(seg segment) {
+		if seg.requires != "" {
+			t.used = append(t.used, seg.requires)
+		}
+	})
+	t.code = compileSegments(nil, t.segments)
+	return t
+}
+
+// ValidateFormat checks that a format is well formed and that its
+// placeholders use known modifiers and valid format specs
+func ValidateFormat(format string) error {
+	_, err := parseFormat(format)
+	return err
+}
+
+type formatParser struct {
+	src string
+	pos int
+	// braces counts the {?...} sections that a } run can close, up to the
+	// innermost [[ section
+	braces int
+}
+
+// parseSegments parses until the closing delimiter of the section opened
+// at start, or the end of the format when close is empty
+func (p *formatParser) parseSegments(close string, start int) ([]segment, error) {
+	var segments []segment
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			segments = append(segments, segment{kind: segmentLiteral, text: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for p.pos < len(p.src) {
+		rest := p.src[p.pos:]
+		switch {
+		case close == "}" && rest[0] == '}':
+			// A } run in a {?...} body closes the open sections before it
+			// escapes braces, so {?a: {?b: {b}}} nests; a brace left over
+			// without a pair is kept as text, as in {?a: [ok]}}
+			n := len(rest) - len(strings.TrimLeft(rest, "}"))
+			switch {
+			case n <= p.braces:
+				p.pos++
+				flush()
+				return segments, nil
+			case n-p.braces >= 2:
+				literal.WriteByte('}')
+				p.pos += 2
+			default:
+				literal.WriteByte('}')
+				p.pos++
+			}
+		case strings.HasPrefix(rest, "{{"), strings.HasPrefix(rest, "}}"):
+			// Doubled braces are literal braces
+			literal.WriteByte(rest[0])
+			p.pos += 2
+		case close != "" && strings.HasPrefix(rest, close):
+			p.pos += len(close)
+			flush()
+			return segments, nil
+		case strings.HasPrefix(rest, "[["):
+			braces := p.braces
+			p.braces = 0
+			children, err := p.parseSection("]]", 2)
+			p.braces = braces
+			if err != nil {
+				return nil, err
+			}
+			flush()
+			segments = append(segments, segment{kind: segmentSection, children: children})
+		case strings.HasPrefix(rest, "{?"):
+			name, n, ok := cutUnquoted(rest[2:], ':')
+			if !ok || name == "" {
+				return nil, fmt.Errorf("optional section at position %d needs a field, as in {?field: text}", p.pos)
+			}
+			if isFieldPath(name) {
+				if _, err := parsePath(name); err != nil {
+					return nil, err
+				}
+			}
+			p.braces++
+			children, err := p.parseSection("}", 2+n+1)
+			p.braces--
+			if err != nil {
+				return nil, err
+			}
+			flush()
+			segments = append(segments, segment{kind: segmentSection, requires: name, children: children})
+		case rest[0] == '{':
+			text, n, ok := cutUnquoted(rest[1:], '}')
+			if !ok {
+				return nil, fmt.Errorf("unterminated { at position %d", p.pos)
+			}
+			ph, err := parsePlaceholder(text)
+			if err != nil {
+				return nil, fmt.Errorf("invalid placeholder {%s}: %v", text, err)
+			}
+			p.pos += 1 + n + 1
+			flush()
+			segments = append(segments, segment{kind: segmentField, text: text, field: ph})
+		case rest[0] == '}':
+			return nil, fmt.Errorf("single } at position %d (write }} for a literal brace)", p.pos)
+		default:
+			literal.WriteByte(rest[0])
+			p.pos++
+		}
+	}
+
+	if close != "" {
+		return nil, fmt.Errorf("unterminated %s at position %d", p.src[start:start+2], start)
+	}
+	flush()
+	return segments, nil
+}
+
+// parseSection parses the body of a section whose opening delimiter is
+// openLen bytes long
+func (p *formatParser) parseSection(close string, openLen int) ([]segment, error) {
+	start := p.pos
+	p.pos += openLen
+	return p.parseSegments(close, start)
+}
+
+// cutUnquoted returns the text before the first sep outside a quoted key,
+// and its length
+func cutUnquoted(s string, sep byte) (string, int, bool) {
+	inQuote := false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && inQuote:
+			i++
+		case c == '"':
+			inQuote = !inQuote
+		case c == sep && !inQuote:
+			return s[:i], i, true
+		}
+	}
+	return "", 0, false
+}
+
+// groupWords groups each whitespace-separated word containing placeholders
+// together with the whitespace before it. Sections stand on their own.
+func groupWords(segments []segment) []segment {
+	var grouped []segment
+	var word []segment
+	hasField := false
+	flush := func() {
+		switch {
+		case len(word) == 0:
+		case hasField:
+			grouped = append(grouped, segment{kind: segmentWord, children: word})
+		default:
+			// Words without fields stay plain text, merged with the text before them
+			for _, seg := range word {
+				if n := len(grouped); n > 0 && grouped[n-1].kind == segmentLiteral {
+					grouped[n-1].text += seg.text
+				} else {
+					grouped = append(grouped, seg)
+				}
+			}
+		}
+		word, hasField = nil, false
+	}
+
+	for _, seg := range segments {
+		switch seg.kind {
+		case segmentField:
+			word = append(word, seg)
+			hasField = true
+		case segmentSection:
+			flush()
+			grouped = append(grouped, seg)
+		case segmentLiteral:
+			// Split the literal at whitespace runs: the end of a word, then
+			// whitespace that leads the next word
+			text := seg.text
+			for text != "" {
+				i := strings.IndexFunc(text, unicode.IsSpace)
+				if i < 0 {
+					word = append(word, segment{kind: segmentLiteral, text: text})
+					break
+				}
+				if i > 0 {
+					word = append(word, segment{kind: segmentLiteral, text: text[:i]})
+				}
+				flush()
+				j := strings.IndexFunc(text[i:], func(r rune) bool { return !unicode.IsSpace(r) })
+				if j < 0 {
+					j = len(text) - i
+				}
+				word = append(word, segment{kind: segmentLiteral, text: text[i : i+j]})
+				text = text[i+j:]
+			}
+		}
+	}
+	flush()
+	return grouped
+}
+
+// walkFields calls fn for every placeholder in the segments
+func walkFields(segments []segment, fn func(placeholder)) {
+	for _, seg := range segments {
+		if seg.kind == segmentField {
+			fn(seg.field)
+		}
+		walkFields(seg.children, fn)
+	}
+}
+
+// walkSections calls fn for every section in the segments
+func walkSections(segments []segment, fn func(segment)) {
+	for _, seg := range segments {
+		if seg.kind == segmentSection {
+			fn(seg)
+		}
+		walkSections(seg.children, fn)
+	}
+}
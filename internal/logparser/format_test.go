@@ -0,0 +1,52 @@
+package logparser
+
+import "testing"
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		name         string
+		format       string
+		wantSegments int
+		wantLevel    bool
+		wantErr      bool
+	}{
+		{name: "Basic", format: "{timestamp} [{level}] {message}", wantSegments: 3, wantLevel: true},
+		{name: "Literal only", format: "no fields here", wantSegments: 1},
+		{name: "Conditional section", format: "{message}{?error: err={error}}", wantSegments: 2},
+		{name: "Optional section", format: "{message}[[ user={user}]]", wantSegments: 2},
+		{name: "Level inside a section", format: "{message}[[ {level}]]", wantSegments: 2, wantLevel: true},
+		{name: "Unterminated placeholder", format: "{level", wantErr: true},
+		{name: "Unterminated conditional section", format: "{?error: err={error}", wantErr: true},
+		{name: "Unterminated optional section", format: "[[ user={user}", wantErr: true},
+		{name: "Conditional section without field", format: "{?: text}", wantErr: true},
+		{name: "Nested conditional sections", format: "{?a: a={a}{?b: b={b}}}", wantSegments: 1},
+		{name: "Brace before conditional section close", format: "{?a: [ok]}}", wantSegments: 1},
+		{name: "Escaped braces in conditional section", format: "{?a: {{{a}}}}", wantSegments: 1},
+		{name: "Conditional section with path", format: "{?http.status: status={http.status}}", wantSegments: 1},
+		{name: "Conditional section with bad path", format: "{?tags[: text}", wantErr: true},
+		{name: "Rest alias", format: "{...}", wantSegments: 1},
+		{name: "Empty placeholder", format: "{message} {}", wantErr: true},
+		{name: "Modifier without field", format: "{|upper}", wantErr: true},
+		{name: "Malformed path", format: "{a[}", wantErr: true},
+		{name: "Unknown modifier", format: "{level|shout}", wantErr: true},
+		{name: "Escaped braces", format: "{{literal}} {{", wantSegments: 1},
+		{name: "Escaped brace before placeholder", format: "{{{level}}}", wantSegments: 1, wantLevel: true},
+		{name: "Single closing brace", format: "{level} }", wantErr: true},
+		{name: "Unbalanced placeholder close", format: "{level}}", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFormat(tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseFormat(%q) error = %v, wantErr %v", tt.format, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got.segments) != tt.wantSegments || got.hasLevel != tt.wantLevel {
+				t.Errorf("parseFormat(%q) = %d segments, hasLevel %v, want %d, %v", tt.format, len(got.segments), got.hasLevel, tt.wantSegments, tt.wantLevel)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package logparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/techarm/jclog/internal/formatter"
+)
+
+// GoTemplate renders records with text/template instead of the {field}
+// format syntax. The record's fields are the template data, so nested keys
+// are reachable as {{.http.request.method}}. Numbers are int64 or float64,
+// so {{if gt .status 499}} compares them, and missing keys render empty.
+type GoTemplate struct {
+	text   string
+	tmpl   *template.Template
+	levels *Levels
+}
+
+// ParseGoTemplate compiles a text/template; ts controls how the time
+// helper reads and formats timestamps, and levels how the color helper
+// reads levels (nil uses the built-in tables)
+func ParseGoTemplate(text string, ts TimeSettings, levels *Levels) (*GoTemplate, error) {
+	tmpl, err := template.New("format").Option("missingkey=zero").Funcs(goTemplateFuncs(ts, levels)).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid go template: %v", err)
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			printMissingEmpty(t.Tree, t.Tree.Root)
+		}
+	}
+	return &GoTemplate{text: text, tmpl: tmpl, levels: levels}, nil
+}
+
+// String returns the template source
+func (g *GoTemplate) String() string {
+	return g.text
+}
+
+// Helper appended to printing actions by printMissingEmpty
+const orEmptyFunc = "orEmpty"
+
+// printMissingEmpty ends every action that prints a value with the orEmpty
+// helper. text/template prints missing keys of map[string]any and nulls as
+// <no value>, even with missingkey=zero, as they are nil interfaces.
+func printMissingEmpty(tree *parse.Tree, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			printMissingEmpty(tree, child)
+		}
+	case *parse.ActionNode:
+		// Declarations such as {{$x := .a}} print nothing
+		if len(n.Pipe.Decl) > 0 {
+			return
+		}
+		ident := parse.NewIdentifier(orEmptyFunc).SetTree(tree).SetPos(n.Pos)
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{NodeType: parse.NodeCommand, Pos: n.Pos, Args: []parse.Node{ident}})
+	case *parse.IfNode:
+		printMissingEmpty(tree, n.List)
+		printMissingEmpty(tree, n.ElseList)
+	case *parse.RangeNode:
+		printMissingEmpty(tree, n.List)
+		printMissingEmpty(tree, n.ElseList)
+	case *parse.WithNode:
+		printMissingEmpty(tree, n.List)
+		printMissingEmpty(tree, n.ElseList)
+	}
+}
+
+// render executes the template on a record's fields; with normalizeLevel
+// the level field holds the level's canonical name
+func (g *GoTemplate) render(rec *record, normalizeLevel bool) (string, error) {
+	data := templateValue(rec.data).(map[string]any)
+	if normalizeLevel {
+		if key, ok := rec.sourceKey("level"); ok {
+			if level, ok := rec.displayLevel(key, g.levels); ok {
+				data[key] = level
+			}
+		}
+	}
+	var b strings.Builder
+	if err := g.tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+// templateValue copies a decoded value with json.Number converted to int64,
+// or to float64 when it is fractional, so template comparisons and
+// arithmetic helpers accept it. Integers beyond int64 stay json.Number and
+// print exactly.
+func templateValue(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if strings.ContainsAny(v.String(), ".eE") {
+			if f, err := v.Float64(); err == nil {
+				return f
+			}
+		}
+		return v
+	case map[string]any:
+		converted := make(map[string]any, len(v))
+		for key, value := range v {
+			converted[key] = templateValue(value)
+		}
+		return converted
+	case []any:
+		converted := make([]any, len(v))
+		for i, value := range v {
+			converted[i] = templateValue(value)
+		}
+		return converted
+	}
+	return v
+}
+
+// goTemplateFuncs returns the helpers available to Go templates
+func goTemplateFuncs(ts TimeSettings, levels *Levels) template.FuncMap {
+	return template.FuncMap{
+		// orEmpty prints missing values as nothing; see printMissingEmpty
+		orEmptyFunc: func(v any) any {
+			if v == nil {
+				return ""
+			}
+			return v
+		},
+		// get resolves a field name or path with aliases: {{get . "level"}}, {{get . "errors[0].code"}}
+		"get": func(data map[string]any, field string) any {
+			v, _ := LookupPath(data, field)
+			return v
+		},
+		// str renders a value the way {field} placeholders do
+		"str": FormatValue,
+		// json encodes a value as compact JSON
+		"json": encodeJSON,
+		// default returns def when the value is missing or empty: {{.user | default "anonymous"}}
+		"default": func(def string, v any) any {
+			if FormatValue(v) == "" {
+				return def
+			}
+			return v
+		},
+		// color colors text by a level: {{color .level .msg}}
+		"color": func(level, text any) string {
+			return formatter.ColorizeByLevel(FormatValue(text), levels.normalize("level", level).String())
+		},
+		// time formats a timestamp with the profile time format in the display
+		// timezone, or with an explicit layout: {{time .time}}, {{time .time "15:04"}}
+		"time": func(v any, layout ...string) string {
+			t, ok := ts.parse(v)
+			if !ok {
+				return FormatValue(v)
+			}
+			format := ts.Format
+			if len(layout) > 0 {
+				format = layout[0]
+			}
+			if format == "" {
+				format = time.RFC3339Nano
+			}
+			return t.In(ts.location()).Format(format)
+		},
+	}
+}
@@ -0,0 +1,109 @@
+package logparser
+
+import (
+	"bufio"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+func TestGoTemplateRender(t *testing.T) {
+	oldNoColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = oldNoColor }()
+
+	oldLocal := time.Local
+	time.Local = time.UTC
+	defer func() { time.Local = oldLocal }()
+
+	data := map[string]any{
+		"time":    "2024-03-20T10:00:00Z",
+		"lvl":     "ERROR",
+		"msg":     "batch failed",
+		"count":   json.Number("1234567890123456789"),
+		"status":  json.Number("503"),
+		"latency": json.Number("0.25"),
+		"note":    "form field was <no value> here",
+		"parent":  nil,
+		"http": map[string]any{
+			"request": map[string]any{"method": "POST"},
+		},
+		"errors": []any{
+			map[string]any{"code": "E1"},
+			map[string]any{"code": "E2"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{name: "Field", template: "{{.msg}}", want: "batch failed"},
+		{name: "Nested key", template: "{{.http.request.method}}", want: "POST"},
+		{name: "Exact number", template: "{{.count}}", want: "1234567890123456789"},
+		{name: "Integer comparison", template: "{{if gt .status 500}}server error{{end}}", want: "server error"},
+		{name: "Float comparison", template: "{{if lt .latency 0.5}}fast{{end}}", want: "fast"},
+		{name: "Fractional number", template: "{{.latency}}", want: "0.25"},
+		{name: "Missing key", template: "[{{.user}}]", want: "[]"},
+		{name: "Null value", template: "[{{.parent}}]", want: "[]"},
+		{name: "Missing key in branches", template: "{{if .msg}}[{{.user}}]{{end}}{{range .errors}}[{{.detail}}]{{end}}", want: "[][][]"},
+		{name: "Missing key in a defined template", template: `{{define "user"}}[{{.user}}]{{end}}{{template "user" .}}`, want: "[]"},
+		{name: "No value text is kept", template: "{{.note}}", want: "form field was <no value> here"},
+		{name: "Alias lookup", template: `{{get . "level"}}`, want: "ERROR"},
+		{name: "Path lookup", template: `{{get . "errors[-1].code"}}`, want: "E2"},
+		{name: "Loop over array", template: "{{range .errors}}- {{.code}}\n{{end}}", want: "- E1\n- E2"},
+		{name: "Time with profile format", template: "{{time .time}}", want: "2024-03-20 10:00:00"},
+		{name: "Time with layout", template: `{{time .time "15:04"}}`, want: "10:00"},
+		{name: "JSON", template: "{{json .http}}", want: `{"request":{"method":"POST"}}`},
+		{name: "Default for missing", template: `{{.user | default "anonymous"}}`, want: "anonymous"},
+		{name: "Default keeps value", template: `{{.msg | default "none"}}`, want: "batch failed"},
+		{name: "Color by level", template: `{{color .lvl .msg}}`, want: color.New(color.FgRed).Sprint("batch failed")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := ParseGoTemplate(tt.template, TimeSettings{Format: "2006-01-02 15:04:05"}, nil)
+			if err != nil {
+				t.Fatalf("ParseGoTemplate() error = %v", err)
+			}
+			got, err := tmpl.render(newRecord(data, 2), true)
+			if err != nil {
+				t.Fatalf("render() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGoTemplateInvalid(t *testing.T) {
+	for _, text := range []string{"{{.msg", "{{unknownFunc .msg}}"} {
+		if _, err := ParseGoTemplate(text, TimeSettings{}, nil); err == nil {
+			t.Errorf("ParseGoTemplate(%q) expected an error", text)
+		}
+	}
+}
+
+func TestProcessLogGoTemplate(t *testing.T) {
+	tmpl, err := ParseGoTemplate(`{{.msg}}{{range .tags}} #{{.}}{{end}}{{if .bad}}{{index .bad 5}}{{end}}`, TimeSettings{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := `{"msg": "first", "tags": ["a", "b"]}
+{"msg": "second", "bad": [1]}
+{"msg": "third"}`
+
+	out := captureStdout(t, func() {
+		ProcessLog(bufio.NewScanner(strings.NewReader(input)), Options{GoTemplate: tmpl})
+	})
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 || lines[0] != "first #a #b" || !strings.HasPrefix(lines[1], "template error:") || lines[2] != "third" {
+		t.Errorf("ProcessLog() output = %q", out)
+	}
+}
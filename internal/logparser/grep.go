@@ -0,0 +1,86 @@
+package logparser
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/fatih/color"
+)
+
+// Highlight color for text matched by --grep
+var grepHighlight = color.New(color.FgHiWhite, color.BgRed, color.Bold).SprintFunc()
+
+// Grep matches a regular expression against every string value of a record
+type Grep struct {
+	pattern *regexp.Regexp
+	invert  bool
+}
+
+// NewGrep compiles a grep pattern
+func NewGrep(pattern string, ignoreCase, invert bool) (*Grep, error) {
+	if ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid grep pattern %q: %v", pattern, err)
+	}
+	return &Grep{pattern: re, invert: invert}, nil
+}
+
+// match reports whether any string value in the record matches the pattern,
+// or whether none does when the match is inverted
+func (g *Grep) match(rec *record) bool {
+	return g.matchValues(rec) != g.invert
+}
+
+func (g *Grep) matchValues(rec *record) bool {
+	for key, v := range rec.data {
+		// A JSON message is searched through its decoded values, not its raw text
+		if key == rec.jsonMessageKey {
+			continue
+		}
+		if g.matchValue(v) {
+			return true
+		}
+	}
+	if rec.jsonMessageKey != "" {
+		for _, v := range rec.message {
+			if g.pattern.MatchString(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchValue walks nested objects and arrays looking for a matching string
+func (g *Grep) matchValue(v any) bool {
+	switch v := v.(type) {
+	case string:
+		return g.pattern.MatchString(v)
+	case map[string]any:
+		for _, item := range v {
+			if g.matchValue(item) {
+				return true
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if g.matchValue(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// highlight marks every match of the pattern in the text
+func (g *Grep) highlight(text string) string {
+	if g.invert {
+		return text
+	}
+	return g.pattern.ReplaceAllStringFunc(text, func(s string) string {
+		return grepHighlight(s)
+	})
+}
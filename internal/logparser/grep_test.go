@@ -0,0 +1,82 @@
+package logparser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/techarm/jclog/internal/formatter"
+)
+
+func TestGrepMatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		pattern    string
+		ignoreCase bool
+		invert     bool
+		data       map[string]any
+		want       bool
+	}{
+		{name: "Top-level value", pattern: "timeout", data: map[string]any{"msg": "upstream timeout"}, want: true},
+		{name: "No match", pattern: "timeout", data: map[string]any{"msg": "ok"}, want: false},
+		{name: "Case sensitive", pattern: "timeout", data: map[string]any{"msg": "Timeout"}, want: false},
+		{name: "Ignore case", pattern: "timeout", ignoreCase: true, data: map[string]any{"msg": "Timeout"}, want: true},
+		{name: "Invert", pattern: "timeout", invert: true, data: map[string]any{"msg": "ok"}, want: true},
+		{name: "Nested object", pattern: "^db-", data: map[string]any{"ctx": map[string]any{"host": "db-1"}}, want: true},
+		{name: "Array value", pattern: "beta", data: map[string]any{"tags": []any{"alpha", "beta"}}, want: true},
+		{name: "Keys do not match", pattern: "host", data: map[string]any{"host": "web-1"}, want: false},
+		{name: "Numbers do not match", pattern: "500", data: map[string]any{"status": float64(500)}, want: false},
+		{name: "JSON message value", pattern: "alice", data: map[string]any{"msg": `{"user": "alice"}`}, want: true},
+		{name: "JSON message key", pattern: "user", data: map[string]any{"msg": `{"user": "alice"}`}, want: false},
+		{name: "JSON message punctuation", pattern: `":`, data: map[string]any{"msg": `{"user": "alice"}`}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := NewGrep(tt.pattern, tt.ignoreCase, tt.invert)
+			if err != nil {
+				t.Fatalf("NewGrep() error = %v", err)
+			}
+			if got := g.match(newRecord(tt.data, 2)); got != tt.want {
+				t.Errorf("match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewGrepInvalidPattern(t *testing.T) {
+	if _, err := NewGrep("(", false, false); err == nil {
+		t.Error("Expected error for invalid pattern")
+	}
+}
+
+func TestGrepHighlight(t *testing.T) {
+	oldNoColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = oldNoColor }()
+
+	g, _ := NewGrep("time", false, false)
+	got := g.highlight("timeout after time")
+	want := grepHighlight("time") + "out after " + grepHighlight("time")
+	if got != want {
+		t.Errorf("highlight() = %q, want %q", got, want)
+	}
+
+	inverted, _ := NewGrep("time", false, true)
+	if got := inverted.highlight("timeout"); got != "timeout" {
+		t.Errorf("highlight() with invert = %q, want %q", got, "timeout")
+	}
+}
+
+func TestGrepHighlightKeepsLevelColor(t *testing.T) {
+	oldNoColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = oldNoColor }()
+
+	g, _ := NewGrep("boom", false, false)
+	line := formatter.ColorizeByLevel(g.highlight("x boom tail"), "ERROR")
+	_, after, _ := strings.Cut(line, "boom")
+	if want := formatter.ColorizeByLevel(" tail", "ERROR"); !strings.HasSuffix(after, want) {
+		t.Errorf("text after the highlight lost the level color: %q", line)
+	}
+}
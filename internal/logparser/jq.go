@@ -0,0 +1,68 @@
+package logparser
+
+import (
+	"fmt"
+
+	"github.com/itchyny/gojq"
+)
+
+// JQ is a compiled jq program that selects and reshapes records before formatting
+type JQ struct {
+	expr string
+	code *gojq.Code
+}
+
+// ParseJQ compiles a jq expression
+func ParseJQ(expr string) (*JQ, error) {
+	query, err := gojq.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jq expression %q: %v", expr, err)
+	}
+	code, err := gojq.Compile(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jq expression %q: %v", expr, err)
+	}
+	return &JQ{expr: expr, code: code}, nil
+}
+
+// String returns the source expression
+func (j *JQ) String() string {
+	return j.expr
+}
+
+// apply runs the program on a record and returns the records to display.
+// Falsey results (false, null) and empty output drop the record, object
+// results replace it, and any other truthy result keeps it unchanged. The
+// record is kept once however many truthy results there are, so .tags[]
+// does not repeat it per tag.
+func (j *JQ) apply(data map[string]any) ([]map[string]any, error) {
+	var results []map[string]any
+	kept := false
+	keep := func() {
+		if !kept {
+			kept = true
+			results = append(results, data)
+		}
+	}
+	iter := j.code.Run(data)
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			break
+		}
+		switch v := v.(type) {
+		case error:
+			return nil, v
+		case nil:
+		case bool:
+			if v {
+				keep()
+			}
+		case map[string]any:
+			results = append(results, v)
+		default:
+			keep()
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,75 @@
+package logparser
+
+import "testing"
+
+func TestParseJQ(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		wantErr bool
+	}{
+		{name: "Select", expr: `select(.level == "ERROR")`},
+		{name: "Reshape", expr: `{time, msg, status: .http.status}`},
+		{name: "Syntax error", expr: `select(.level ==`, wantErr: true},
+		{name: "Unknown function", expr: `nosuchfunc`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseJQ(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseJQ(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJQApply(t *testing.T) {
+	data := map[string]any{
+		"level": "ERROR",
+		"msg":   "failed",
+		"http":  map[string]any{"status": float64(503)},
+		"tags":  []any{"a", "b"},
+	}
+
+	tests := []struct {
+		name      string
+		expr      string
+		wantCount int
+		wantField string
+		wantValue string
+		wantErr   bool
+	}{
+		{name: "Select keeps record", expr: `select(.level == "ERROR")`, wantCount: 1, wantField: "msg", wantValue: "failed"},
+		{name: "Select drops record", expr: `select(.level == "INFO")`, wantCount: 0},
+		{name: "False drops record", expr: `.level == "INFO"`, wantCount: 0},
+		{name: "Null drops record", expr: `.missing`, wantCount: 0},
+		{name: "True keeps record", expr: `.http.status >= 500`, wantCount: 1, wantField: "level", wantValue: "ERROR"},
+		{name: "Object replaces record", expr: `{message: .msg, status: .http.status}`, wantCount: 1, wantField: "status", wantValue: "503"},
+		{name: "Multiple outputs", expr: `.tags[] | {tag: .}`, wantCount: 2, wantField: "tag", wantValue: "a"},
+		{name: "Multiple scalars keep record once", expr: `.tags[]`, wantCount: 1, wantField: "msg", wantValue: "failed"},
+		{name: "Multiple truthy results keep record once", expr: `.level, .msg, true`, wantCount: 1, wantField: "level", wantValue: "ERROR"},
+		{name: "Runtime error", expr: `.msg | tonumber`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jq, err := ParseJQ(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseJQ(%q) error = %v", tt.expr, err)
+			}
+			got, err := jq.apply(data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != tt.wantCount {
+				t.Fatalf("apply() returned %d records, want %d", len(got), tt.wantCount)
+			}
+			if tt.wantCount > 0 {
+				if value, _ := newRecord(got[0], 2).lookupString(tt.wantField); value != tt.wantValue {
+					t.Errorf("%s = %q, want %q", tt.wantField, value, tt.wantValue)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package logparser
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Level is a canonical log level. The level values of every supported
+// framework are normalized to one, ordered from least to most severe.
+type Level uint8
+
+const (
+	// LevelUnknown is a value that is not a recognizable level
+	LevelUnknown Level = iota
+	LevelTrace
+	LevelDebug
+	LevelInfo
+	LevelNotice
+	LevelWarn
+	LevelError
+	LevelCritical
+	LevelAlert
+	LevelFatal
+)
+
+var levelNames = [...]string{"", "TRACE", "DEBUG", "INFO", "NOTICE", "WARN", "ERROR", "CRITICAL", "ALERT", "FATAL"}
+
+// String returns the canonical name of the level, or "" when it is unknown
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// levelSynonyms maps upper-cased level names and their synonyms across
+// frameworks to canonical levels: zap's dpanic and panic, syslog's err,
+// crit and emerg, GCP's WARNING and EMERGENCY, .NET's Information
+var levelSynonyms = map[string]Level{
+	"TRACE":       LevelTrace,
+	"DEBUG":       LevelDebug,
+	"INFO":        LevelInfo,
+	"INFORMATION": LevelInfo,
+	"NOTICE":      LevelNotice,
+	"WARN":        LevelWarn,
+	"WARNING":     LevelWarn,
+	"ERROR":       LevelError,
+	"ERR":         LevelError,
+	"CRIT":        LevelCritical,
+	"CRITICAL":    LevelCritical,
+	"DPANIC":      LevelCritical,
+	"ALERT":       LevelAlert,
+	"PANIC":       LevelAlert,
+	"FATAL":       LevelFatal,
+	"EMERG":       LevelFatal,
+	"EMERGENCY":   LevelFatal,
+}
+
+// Names of the level tables a profile can override. The names table holds
+// level names and synonyms; the others number levels the way a framework
+// does.
+const (
+	levelTableNames  = "names"
+	levelTableBunyan = "bunyan"
+	levelTableSyslog = "syslog"
+	levelTableOTel   = "otel"
+	levelTableGCP    = "gcp"
+)
+
+// defaultLevelNumbers are the built-in numeric level tables. A number
+// between two entries takes the level of the entry below it, so pino's
+// custom levels and OpenTelemetry's TRACE2-4, INFO2-4, ... fall in place.
+var defaultLevelNumbers = map[string]map[string]string{
+	// bunyan and pino
+	levelTableBunyan: {"10": "TRACE", "20": "DEBUG", "30": "INFO", "40": "WARN", "50": "ERROR", "60": "FATAL"},
+	// syslog severities, most severe first
+	levelTableSyslog: {"0": "EMERG", "1": "ALERT", "2": "CRIT", "3": "ERR", "4": "WARNING", "5": "NOTICE", "6": "INFO", "7": "DEBUG"},
+	// OpenTelemetry SeverityNumber 1-24
+	levelTableOTel: {"1": "TRACE", "5": "DEBUG", "9": "INFO", "13": "WARN", "17": "ERROR", "21": "FATAL"},
+	// Google Cloud Logging LogSeverity
+	levelTableGCP: {"100": "DEBUG", "200": "INFO", "300": "NOTICE", "400": "WARNING", "500": "ERROR", "600": "CRITICAL", "700": "ALERT", "800": "EMERGENCY"},
+}
+
+// levelNumber is an entry of a numeric level table
+type levelNumber struct {
+	value float64
+	level Level
+}
+
+// Levels normalizes level values to canonical levels. The zero value is
+// not usable; a nil *Levels uses the built-in tables.
+type Levels struct {
+	// mappings replace exact level values before they are normalized
+	mappings map[string]string
+	names    map[string]Level
+	// numeric tables by name, sorted by value
+	numbers map[string][]levelNumber
+}
+
+var defaultLevels, _ = NewLevels(nil, nil)
+
+// NewLevels creates a normalizer from a profile's level_mappings and
+// level_tables. Mappings replace exact values, such as "5": "ERROR", before
+// anything else. Tables add entries to or override the built-in names,
+// bunyan, syslog, otel and gcp tables; their values are level names.
+func NewLevels(mappings map[string]string, tables map[string]map[string]string) (*Levels, error) {
+	l := &Levels{
+		mappings: mappings,
+		names:    maps.Clone(levelSynonyms),
+		numbers:  make(map[string][]levelNumber, len(defaultLevelNumbers)),
+	}
+	for table := range tables {
+		if _, ok := defaultLevelNumbers[table]; !ok && table != levelTableNames {
+			return nil, fmt.Errorf("unknown level table %q (available: names, bunyan, syslog, otel, gcp)", table)
+		}
+	}
+
+	for key, name := range tables[levelTableNames] {
+		level, err := levelByName(levelTableNames, key, name)
+		if err != nil {
+			return nil, err
+		}
+		l.names[strings.ToUpper(strings.TrimSpace(key))] = level
+	}
+
+	for table, defaults := range defaultLevelNumbers {
+		entries := maps.Clone(defaults)
+		maps.Copy(entries, tables[table])
+		numbers := make([]levelNumber, 0, len(entries))
+		for key, name := range entries {
+			value, err := strconv.ParseFloat(strings.TrimSpace(key), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid level table %q: %q is not a number", table, key)
+			}
+			level, err := levelByName(table, key, name)
+			if err != nil {
+				return nil, err
+			}
+			numbers = append(numbers, levelNumber{value, level})
+		}
+		slices.SortFunc(numbers, func(a, b levelNumber) int {
+			return cmp.Compare(a.value, b.value)
+		})
+		l.numbers[table] = numbers
+	}
+	return l, nil
+}
+
+// levelByName resolves the level name of a table entry with the built-in
+// names, so overrides do not depend on each other
+func levelByName(table, key, name string) (Level, error) {
+	level, ok := levelSynonyms[strings.ToUpper(strings.TrimSpace(name))]
+	if !ok {
+		return LevelUnknown, fmt.Errorf("invalid level table %q: unknown level %q for %q", table, name, key)
+	}
+	return level, nil
+}
+
+// ParseLevel returns the canonical level of a level name or numeric code
+func ParseLevel(level string) (Level, error) {
+	return defaultLevels.Parse(level)
+}
+
+// Parse returns the canonical level of a level name or numeric code, as
+// given to --min-level and --max-level
+func (l *Levels) Parse(level string) (Level, error) {
+	if normalized := l.normalize("level", level); normalized != LevelUnknown {
+		return normalized, nil
+	}
+	return LevelUnknown, fmt.Errorf("unknown log level %q", level)
+}
+
+// normalize returns the canonical level of a value read from a level field.
+// Names are looked up case-insensitively; numbers are read with the table of
+// the framework the field belongs to.
+func (l *Levels) normalize(field string, v any) Level {
+	if l == nil {
+		l = defaultLevels
+	}
+	text := strings.TrimSpace(FormatValue(v))
+	// Mapped numbers are bunyan-style levels, which OpenTelemetry and Google
+	// Cloud severities in the same range do not mean
+	if mapped, ok := l.mappings[text]; ok && !(hasOwnLevelNumbers(field) && isNumber(text)) {
+		text = mapped
+	}
+	if level, ok := l.names[strings.ToUpper(text)]; ok {
+		return level
+	}
+	n, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return LevelUnknown
+	}
+
+	numbers := l.numbers[levelNumberTable(field, n)]
+	i, found := slices.BinarySearchFunc(numbers, n, func(e levelNumber, n float64) int {
+		return cmp.Compare(e.value, n)
+	})
+	switch {
+	case found:
+		return numbers[i].level
+	case i > 0:
+		return numbers[i-1].level
+	}
+	return LevelUnknown
+}
+
+// levelNumberTable picks the table for a numeric level by the field it was
+// read from. OpenTelemetry and Google Cloud keep their numbers in fields of
+// their own; in other fields 0-7 are syslog severities, which no other scale
+// uses.
+func levelNumberTable(field string, n float64) string {
+	switch {
+	case field == "severityNumber" || field == "SeverityNumber":
+		return levelTableOTel
+	case field == "severity":
+		return levelTableGCP
+	case n < 8:
+		return levelTableSyslog
+	}
+	return levelTableBunyan
+}
+
+// hasOwnLevelNumbers reports whether a field numbers levels on a scale of
+// its own, OpenTelemetry's or Google Cloud's
+func hasOwnLevelNumbers(field string) bool {
+	return levelNumberTable(field, 8) != levelTableBunyan
+}
+
+// isNumber reports whether text is a number
+func isNumber(text string) bool {
+	_, err := strconv.ParseFloat(text, 64)
+	return err == nil
+}
+
+// matchLevel checks whether the record's level lies within the bounds.
+// Records without a recognizable level never match when a bound is set.
+func matchLevel(rec *record, minLevel, maxLevel Level, levels *Levels) bool {
+	level := rec.level(levels)
+	if level == LevelUnknown {
+		return false
+	}
+	if minLevel != LevelUnknown && level < minLevel {
+		return false
+	}
+	if maxLevel != LevelUnknown && level > maxLevel {
+		return false
+	}
+	return true
+}
+
+// isLevelField reports whether a filter field names the level
+func isLevelField(field string) bool {
+	return field == "level" || slices.Contains(FieldAliases["level"], field)
+}
+
+// withLevels returns a copy of a filter on the level that matches every
+// spelling of a level: the value set of = and != also holds the canonical
+// names of its values, so level=warning matches records normalized to WARN,
+// and >, >=, < and <= order levels by severity
+func (f Filter) withLevels(levels *Levels) Filter {
+	if !isLevelField(f.Field) {
+		return f
+	}
+	switch f.Operator {
+	case ">", ">=", "<", "<=":
+		f.byLevel, f.levels = true, levels
+		return f
+	case "=", "!=":
+	default:
+		return f
+	}
+	values := slices.Clone(f.values)
+	for _, value := range f.values {
+		if level := levels.normalize(f.Field, value); level != LevelUnknown && !slices.Contains(values, level.String()) {
+			values = append(values, level.String())
+		}
+	}
+	f.values = values
+	return f
+}
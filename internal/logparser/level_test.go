@@ -0,0 +1,311 @@
+package logparser
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/techarm/jclog/internal/formatter"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		level   string
+		want    Level
+		wantErr bool
+	}{
+		{level: "WARN", want: LevelWarn},
+		{level: "warning", want: LevelWarn},
+		{level: "err", want: LevelError},
+		{level: "crit", want: LevelCritical},
+		{level: "dpanic", want: LevelCritical},
+		{level: "panic", want: LevelAlert},
+		{level: "Fatal", want: LevelFatal},
+		{level: "30", want: LevelInfo},
+		{level: "4", want: LevelWarn},
+		{level: "verbose", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			got, err := ParseLevel(tt.level)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLevel(%q) error = %v, wantErr %v", tt.level, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLevel(%q) = %v, want %v", tt.level, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeLevel(t *testing.T) {
+	// level_mappings of the default config
+	defaultMappings := map[string]string{"10": "TRACE", "20": "DEBUG", "30": "INFO", "40": "WARN", "50": "ERROR", "60": "FATAL"}
+
+	tests := []struct {
+		name     string
+		field    string
+		value    any
+		mappings map[string]string
+		want     Level
+	}{
+		{name: "Lowercase name", field: "level", value: "warning", want: LevelWarn},
+		{name: "zap dpanic", field: "level", value: "dpanic", want: LevelCritical},
+		{name: "Bunyan number", field: "level", value: float64(50), want: LevelError},
+		{name: "pino custom number", field: "level", value: float64(35), want: LevelInfo},
+		{name: "Number above the table", field: "level", value: float64(70), want: LevelFatal},
+		{name: "Numeric string", field: "level", value: "20", want: LevelDebug},
+		{name: "syslog emergency", field: "level", value: float64(0), want: LevelFatal},
+		{name: "syslog error", field: "level", value: float64(3), want: LevelError},
+		{name: "syslog debug", field: "lvl", value: "7", want: LevelDebug},
+		{name: "OpenTelemetry INFO", field: "severityNumber", value: float64(9), want: LevelInfo},
+		{name: "OpenTelemetry WARN3", field: "SeverityNumber", value: float64(15), want: LevelWarn},
+		{name: "OpenTelemetry FATAL4", field: "severityNumber", value: float64(24), want: LevelFatal},
+		{name: "OpenTelemetry below range", field: "severityNumber", value: float64(0), want: LevelUnknown},
+		{name: "GCP name", field: "severity", value: "EMERGENCY", want: LevelFatal},
+		{name: "GCP number", field: "severity", value: float64(400), want: LevelWarn},
+		{name: "GCP default", field: "severity", value: "DEFAULT", want: LevelUnknown},
+		{name: "GCP default number", field: "severity", value: float64(0), want: LevelUnknown},
+		{name: "Mappings skip OpenTelemetry INFO2", field: "severityNumber", value: float64(10), mappings: defaultMappings, want: LevelInfo},
+		{name: "Mappings skip OpenTelemetry ERROR4", field: "severityNumber", value: float64(20), mappings: defaultMappings, want: LevelError},
+		{name: "Mappings skip GCP numbers", field: "severity", value: "10", mappings: defaultMappings, want: LevelUnknown},
+		{name: "Mappings apply to level", field: "level", value: float64(10), mappings: map[string]string{"10": "ERROR"}, want: LevelError},
+		{name: "Unknown name", field: "level", value: "chatty", want: LevelUnknown},
+		{name: "Negative number", field: "level", value: float64(-1), want: LevelUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var levels *Levels
+			if tt.mappings != nil {
+				var err error
+				if levels, err = NewLevels(tt.mappings, nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := levels.normalize(tt.field, tt.value); got != tt.want {
+				t.Errorf("normalize(%q, %v) = %v, want %v", tt.field, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewLevels(t *testing.T) {
+	levels, err := NewLevels(map[string]string{"5": "ERROR"}, map[string]map[string]string{
+		"names":  {"verbose": "TRACE", "warn": "ERROR"},
+		"bunyan": {"35": "NOTICE"},
+		"syslog": {"6": "notice"},
+	})
+	if err != nil {
+		t.Fatalf("NewLevels() error = %v", err)
+	}
+
+	tests := []struct {
+		field string
+		value any
+		want  Level
+	}{
+		{field: "level", value: float64(5), want: LevelError},
+		{field: "level", value: "Verbose", want: LevelTrace},
+		{field: "level", value: "warn", want: LevelError},
+		{field: "level", value: "warning", want: LevelWarn},
+		{field: "level", value: float64(35), want: LevelNotice},
+		{field: "level", value: float64(38), want: LevelNotice},
+		{field: "level", value: float64(40), want: LevelWarn},
+		{field: "level", value: float64(6), want: LevelNotice},
+	}
+	for _, tt := range tests {
+		if got := levels.normalize(tt.field, tt.value); got != tt.want {
+			t.Errorf("normalize(%q, %v) = %v, want %v", tt.field, tt.value, got, tt.want)
+		}
+	}
+	if got, err := levels.Parse("verbose"); err != nil || got != LevelTrace {
+		t.Errorf("Parse(verbose) = %v, %v, want TRACE", got, err)
+	}
+
+	for name, tables := range map[string]map[string]map[string]string{
+		"Unknown table":     {"log4j": {"1": "INFO"}},
+		"Unknown level":     {"names": {"verbose": "LOUD"}},
+		"Non-numeric key":   {"otel": {"nine": "INFO"}},
+		"Unknown level num": {"gcp": {"900": "APOCALYPSE"}},
+	} {
+		if _, err := NewLevels(nil, tables); err == nil {
+			t.Errorf("%s: NewLevels() accepted %v", name, tables)
+		}
+	}
+}
+
+func TestMatchLevel(t *testing.T) {
+	levels, err := NewLevels(map[string]string{"5": "ERROR"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		data     map[string]any
+		minLevel string
+		maxLevel string
+		want     bool
+	}{
+		{name: "Equal to minimum", data: map[string]any{"level": "WARN"}, minLevel: "WARN", want: true},
+		{name: "Above minimum", data: map[string]any{"level": "error"}, minLevel: "WARN", want: true},
+		{name: "Below minimum", data: map[string]any{"level": "info"}, minLevel: "WARN", want: false},
+		{name: "Synonym", data: map[string]any{"level": "warning"}, minLevel: "WARN", want: true},
+		{name: "Bunyan numeric level", data: map[string]any{"level": float64(40)}, minLevel: "WARN", want: true},
+		{name: "Bunyan numeric below", data: map[string]any{"level": float64(30)}, minLevel: "WARN", want: false},
+		{name: "Custom mapping", data: map[string]any{"level": float64(5)}, minLevel: "ERROR", want: true},
+		{name: "Severity alias", data: map[string]any{"severity": "CRITICAL"}, minLevel: "ERROR", want: true},
+		{name: "syslog severity", data: map[string]any{"level": float64(2)}, minLevel: "ERROR", want: true},
+		{name: "OpenTelemetry severity", data: map[string]any{"severityNumber": float64(13)}, minLevel: "ERROR", want: false},
+		{name: "Above maximum", data: map[string]any{"level": "ERROR"}, maxLevel: "INFO", want: false},
+		{name: "Within range", data: map[string]any{"level": "INFO"}, minLevel: "DEBUG", maxLevel: "WARN", want: true},
+		{name: "Missing level", data: map[string]any{"msg": "no level"}, minLevel: "TRACE", want: false},
+		{name: "Unknown level", data: map[string]any{"level": "verbose"}, minLevel: "TRACE", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var minLevel, maxLevel Level
+			if tt.minLevel != "" {
+				minLevel, _ = ParseLevel(tt.minLevel)
+			}
+			if tt.maxLevel != "" {
+				maxLevel, _ = ParseLevel(tt.maxLevel)
+			}
+			if got := matchLevel(newRecord(tt.data, 2), minLevel, maxLevel, levels); got != tt.want {
+				t.Errorf("matchLevel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLevelFilters(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		data   map[string]any
+		want   bool
+	}{
+		{name: "Synonym in filter", filter: "level=warning", data: map[string]any{"level": "WARN"}, want: true},
+		{name: "Synonym in record", filter: "level=WARN", data: map[string]any{"level": "warning"}, want: true},
+		{name: "Number in record", filter: "level=ERROR", data: map[string]any{"level": float64(50)}, want: true},
+		{name: "OpenTelemetry record", filter: "level=ERROR,FATAL", data: map[string]any{"severityNumber": float64(17)}, want: true},
+		{name: "Raw value still matches", filter: "level=warning", data: map[string]any{"level": "warning"}, want: true},
+		{name: "Other level", filter: "level=WARN", data: map[string]any{"level": "info"}, want: false},
+		{name: "Not equal to synonym", filter: "level!=WARN", data: map[string]any{"level": "warning"}, want: false},
+		{name: "Not equal to other level", filter: "level!=WARN", data: map[string]any{"level": "info"}, want: true},
+		{name: "Unknown level compares raw", filter: "level=verbose", data: map[string]any{"level": "verbose"}, want: true},
+		{name: "At or above by severity", filter: "level>=WARN", data: map[string]any{"level": "CRITICAL"}, want: true},
+		{name: "Above by severity", filter: "level>WARN", data: map[string]any{"level": "ERROR"}, want: true},
+		{name: "Number above by severity", filter: "level>warning", data: map[string]any{"level": float64(50)}, want: true},
+		{name: "Below by severity", filter: "level>=WARN", data: map[string]any{"level": "info"}, want: false},
+		{name: "At or below by severity", filter: "severity<=INFO", data: map[string]any{"severity": "WARNING"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filters := prepareFilters([]Filter{mustParseFilter(t, tt.filter)}, nil, TimeSettings{})
+			if got := matchFilters(newRecord(tt.data, 2), filters, nil); got != tt.want {
+				t.Errorf("matchFilters(%s) = %v, want %v", tt.filter, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLevelColorsAndNames(t *testing.T) {
+	oldNoColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = oldNoColor }()
+
+	input := `{"level": "warning", "msg": "zap"}
+{"level": 3, "msg": "syslog"}
+{"severityNumber": 21, "severityText": "CRITICAL", "msg": "otel text"}
+{"severityNumber": 10, "msg": "otel number"}
+{"severity": "NOTICE", "msg": "gcp"}
+{"level": "chatty", "msg": "unknown"}`
+
+	tests := []struct {
+		name     string
+		rawLevel bool
+		want     []string
+	}{
+		{
+			name: "Normalized names",
+			want: []string{
+				formatter.ColorizeByLevel("[WARN] zap", "WARN"),
+				formatter.ColorizeByLevel("[ERROR] syslog", "ERROR"),
+				formatter.ColorizeByLevel("[CRITICAL] otel text", "CRITICAL"),
+				formatter.ColorizeByLevel("[INFO] otel number", "INFO"),
+				formatter.ColorizeByLevel("[NOTICE] gcp", "NOTICE"),
+				"[chatty] unknown",
+			},
+		},
+		{
+			name:     "Raw values, normalized colors",
+			rawLevel: true,
+			want: []string{
+				formatter.ColorizeByLevel("[warning] zap", "WARN"),
+				formatter.ColorizeByLevel("[3] syslog", "ERROR"),
+				formatter.ColorizeByLevel("[CRITICAL] otel text", "CRITICAL"),
+				formatter.ColorizeByLevel("[10] otel number", "INFO"),
+				formatter.ColorizeByLevel("[NOTICE] gcp", "NOTICE"),
+				"[chatty] unknown",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := captureStdout(t, func() {
+				ProcessLog(bufio.NewScanner(strings.NewReader(input)), Options{Format: "[{level}] {message}", RawLevel: tt.rawLevel})
+			})
+			if got, want := out, strings.Join(tt.want, "\n")+"\n"; got != want {
+				t.Errorf("output = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestProcessLogMatchesLevelsAsWritten(t *testing.T) {
+	input := `{"level": "warning", "msg": "disk almost full"}
+{"level": "info", "msg": "started"}`
+
+	mustGrep := func(pattern string) *Grep {
+		g, err := NewGrep(pattern, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return g
+	}
+	goTemplate, err := ParseGoTemplate("{{.level}} {{.msg}}", TimeSettings{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{name: "Grep sees the level as written", opts: Options{Format: "{msg}", Grep: mustGrep("warning")}, want: "disk almost full"},
+		{name: "Regex filter sees the level as written", opts: Options{Format: "{msg}", Filters: []Filter{mustParseFilter(t, "level=~^warn")}}, want: "disk almost full"},
+		{name: "Equality filter sees the normalized level", opts: Options{Format: "{msg}", Filters: []Filter{mustParseFilter(t, "level=WARN")}}, want: "disk almost full"},
+		{name: "Placeholder shows the normalized level", opts: Options{Format: "[{level}] {msg}", Filters: []Filter{mustParseFilter(t, "level=WARN")}}, want: "[WARN] disk almost full"},
+		{name: "Rest shows the level as written", opts: Options{Format: "{rest}", Filters: []Filter{mustParseFilter(t, "level=WARN")}}, want: `level=warning msg="disk almost full"`},
+		{name: "Go template shows the normalized level", opts: Options{GoTemplate: goTemplate, Filters: []Filter{mustParseFilter(t, "level=WARN")}}, want: "WARN disk almost full"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := captureStdout(t, func() {
+				ProcessLog(bufio.NewScanner(strings.NewReader(input)), tt.opts)
+			})
+			if got := stripANSI(strings.TrimSuffix(out, "\n")); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package logparser
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/techarm/jclog/internal/formatter"
+)
+
+// modifier transforms a placeholder value, as in {caller|basename|pad:20}
+type modifier struct {
+	name  string
+	apply func(v any) any
+	// keepsMissing marks modifiers that also run on missing values
+	keepsMissing bool
+}
+
+// modifierFactory builds a modifier from its colon-separated arguments
+type modifierFactory func(args []string) (func(v any) any, error)
+
+// modifierRegistry maps modifier names to their factories
+var modifierRegistry = map[string]modifierFactory{
+	"upper":        noArgs(func(v any) any { return strings.ToUpper(FormatValue(v)) }),
+	"lower":        noArgs(func(v any) any { return strings.ToLower(FormatValue(v)) }),
+	"trunc":        truncModifier,
+	"pad":          padModifier,
+	"default":      defaultModifier,
+	"json":         noArgs(func(v any) any { return encodeJSON(v) }),
+	"basename":     noArgs(func(v any) any { return filepath.Base(FormatValue(v)) }),
+	"dirname":      noArgs(func(v any) any { return filepath.Dir(FormatValue(v)) }),
+	"bytes":        noArgs(bytesModifier),
+	"duration":     durationModifier,
+	"round":        roundModifier,
+	"replace":      replaceModifier,
+	"base64decode": noArgs(base64DecodeModifier),
+	"urldecode":    noArgs(urlDecodeModifier),
+}
+
+// parseModifiers parses a |-separated modifier chain such as basename|pad:20
+func parseModifiers(chain string) ([]modifier, error) {
+	if chain == "" {
+		return nil, nil
+	}
+
+	var mods []modifier
+	for _, spec := range strings.Split(chain, "|") {
+		name, rawArgs, hasArgs := strings.Cut(spec, ":")
+		if _, ok := relativeModes[name]; ok {
+			return nil, fmt.Errorf("modifier %q must come first", name)
+		}
+		factory, ok := modifierRegistry[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown modifier %q (available: %s)", name, strings.Join(modifierNames(), ", "))
+		}
+		var args []string
+		if hasArgs {
+			args = strings.Split(rawArgs, ":")
+		}
+		apply, err := factory(args)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modifier %q: %v", spec, err)
+		}
+		mods = append(mods, modifier{name: name, apply: apply, keepsMissing: name == "default"})
+	}
+	return mods, nil
+}
+
+// applyModifiers runs a modifier chain over a value. Missing values (nil or
+// empty) pass through untouched until a modifier such as default fills them.
+func applyModifiers(v any, mods []modifier) any {
+	for _, m := range mods {
+		if FormatValue(v) == "" && !m.keepsMissing {
+			continue
+		}
+		v = m.apply(v)
+	}
+	return v
+}
+
+func modifierNames() []string {
+	names := make([]string, 0, len(modifierRegistry)+len(relativeModes))
+	for name := range modifierRegistry {
+		names = append(names, name)
+	}
+	for name := range relativeModes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Modifier implementations
+
+func noArgs(apply func(v any) any) modifierFactory {
+	return func(args []string) (func(v any) any, error) {
+		if len(args) > 0 {
+			return nil, fmt.Errorf("takes no arguments")
+		}
+		return apply, nil
+	}
+}
+
+// intArg parses the single non-negative integer argument of trunc, pad and round
+func intArg(args []string) (int, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("takes a single number argument")
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("bad number %q", args[0])
+	}
+	return n, nil
+}
+
+// truncModifier cuts values to at most N characters
+func truncModifier(args []string) (func(v any) any, error) {
+	n, err := intArg(args)
+	if err != nil {
+		return nil, err
+	}
+	return func(v any) any {
+		s := FormatValue(v)
+		if utf8.RuneCountInString(s) <= n {
+			return s
+		}
+		return string([]rune(s)[:n])
+	}, nil
+}
+
+// padModifier right-pads values with spaces to N terminal columns
+func padModifier(args []string) (func(v any) any, error) {
+	n, err := intArg(args)
+	if err != nil {
+		return nil, err
+	}
+	return func(v any) any {
+		return formatter.PadWidth(FormatValue(v), n, '<')
+	}, nil
+}
+
+// defaultModifier replaces missing values; the text may itself contain colons
+func defaultModifier(args []string) (func(v any) any, error) {
+	text := strings.Join(args, ":")
+	return func(v any) any {
+		if FormatValue(v) == "" {
+			return text
+		}
+		return v
+	}, nil
+}
+
+// floatArg converts numbers and numeric strings to float64
+func floatArg(v any) (float64, bool) {
+	if n, ok := numberValue(v); ok {
+		return n, true
+	}
+	n, err := strconv.ParseFloat(FormatValue(v), 64)
+	return n, err == nil
+}
+
+// bytesModifier renders a byte count with binary units, e.g. 1536 as 1.5 KiB
+func bytesModifier(v any) any {
+	n, ok := floatArg(v)
+	if !ok {
+		return v
+	}
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+	i := 0
+	for math.Abs(n) >= 1024 && i < len(units)-1 {
+		n /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%s B", strconv.FormatFloat(n, 'f', -1, 64))
+	}
+	return fmt.Sprintf("%s %s", strconv.FormatFloat(math.Round(n*10)/10, 'f', -1, 64), units[i])
+}
+
+// Units accepted by the duration modifier
+var durationUnits = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+}
+
+// durationModifier renders a number of ms (or the given unit) as a Go
+// duration such as 1.5s or 2m3.5s
+func durationModifier(args []string) (func(v any) any, error) {
+	unit := time.Millisecond
+	if len(args) > 1 {
+		return nil, fmt.Errorf("takes a single unit argument")
+	}
+	if len(args) == 1 {
+		u, ok := durationUnits[args[0]]
+		if !ok {
+			return nil, fmt.Errorf("unknown unit %q (use ns, us, ms or s)", args[0])
+		}
+		unit = u
+	}
+	return func(v any) any {
+		n, ok := floatArg(v)
+		if !ok {
+			if d, err := time.ParseDuration(FormatValue(v)); err == nil {
+				return d.String()
+			}
+			return v
+		}
+		d := time.Duration(n * float64(unit))
+		if d >= time.Second || d <= -time.Second {
+			d = d.Round(time.Millisecond)
+		}
+		return d.String()
+	}, nil
+}
+
+// roundModifier rounds numbers to N decimal places
+func roundModifier(args []string) (func(v any) any, error) {
+	places, err := intArg(args)
+	if err != nil {
+		return nil, err
+	}
+	return func(v any) any {
+		n, ok := floatArg(v)
+		if !ok {
+			return v
+		}
+		return strconv.FormatFloat(n, 'f', places, 64)
+	}, nil
+}
+
+// replaceModifier replaces every occurrence of one string with another
+func replaceModifier(args []string) (func(v any) any, error) {
+	if len(args) != 2 || args[0] == "" {
+		return nil, fmt.Errorf("takes two arguments, replace:old:new")
+	}
+	return func(v any) any {
+		return strings.ReplaceAll(FormatValue(v), args[0], args[1])
+	}, nil
+}
+
+// base64DecodeModifier decodes standard or URL-safe base64, padded or not;
+// values that are not valid base64 are left unchanged
+func base64DecodeModifier(v any) any {
+	s := FormatValue(v)
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		if decoded, err := enc.DecodeString(s); err == nil {
+			return string(decoded)
+		}
+	}
+	return v
+}
+
+// urlDecodeModifier decodes percent-encoded text; invalid values are left unchanged
+func urlDecodeModifier(v any) any {
+	decoded, err := url.QueryUnescape(FormatValue(v))
+	if err != nil {
+		return v
+	}
+	return decoded
+}
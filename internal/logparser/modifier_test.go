@@ -0,0 +1,82 @@
+package logparser
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestApplyModifiers(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		chain string
+		want  string
+	}{
+		{name: "Upper", value: "info", chain: "upper", want: "INFO"},
+		{name: "Lower", value: "WARN", chain: "lower", want: "warn"},
+		{name: "Trunc", value: "connection refused", chain: "trunc:10", want: "connection"},
+		{name: "Trunc counts characters", value: "日本語のログ", chain: "trunc:3", want: "日本語"},
+		{name: "Pad", value: "ok", chain: "pad:5", want: "ok   "},
+		{name: "Pad longer value", value: "too long", chain: "pad:3", want: "too long"},
+		{name: "Default for missing", value: nil, chain: "default:anonymous", want: "anonymous"},
+		{name: "Default keeps colons", value: "", chain: "default:n/a:none", want: "n/a:none"},
+		{name: "Default keeps value", value: "alice", chain: "default:anonymous", want: "alice"},
+		{name: "Missing skips modifiers", value: nil, chain: "upper|pad:5", want: ""},
+		{name: "JSON string", value: `say "hi"`, chain: "json", want: `"say \"hi\""`},
+		{name: "JSON number", value: json.Number("42"), chain: "json", want: "42"},
+		{name: "JSON object", value: map[string]any{"a": true}, chain: "json", want: `{"a":true}`},
+		{name: "Basename", value: "/app/src/main.go", chain: "basename", want: "main.go"},
+		{name: "Dirname", value: "/app/src/main.go", chain: "dirname", want: "/app/src"},
+		{name: "Bytes", value: json.Number("1536"), chain: "bytes", want: "1.5 KiB"},
+		{name: "Small bytes", value: json.Number("512"), chain: "bytes", want: "512 B"},
+		{name: "Large bytes", value: "3221225472", chain: "bytes", want: "3 GiB"},
+		{name: "Duration in ms", value: json.Number("1500"), chain: "duration", want: "1.5s"},
+		{name: "Duration with unit", value: json.Number("125"), chain: "duration:s", want: "2m5s"},
+		{name: "Duration below a second", value: 0.25, chain: "duration", want: "250µs"},
+		{name: "Duration string", value: "90s", chain: "duration", want: "1m30s"},
+		{name: "Round", value: json.Number("0.98765"), chain: "round:2", want: "0.99"},
+		{name: "Round non-number", value: "n/a", chain: "round:2", want: "n/a"},
+		{name: "Replace", value: "a-b-c", chain: "replace:-:/", want: "a/b/c"},
+		{name: "Base64 decode", value: "aGVsbG8=", chain: "base64decode", want: "hello"},
+		{name: "Base64 decode unpadded", value: "aGVsbG8", chain: "base64decode", want: "hello"},
+		{name: "URL decode", value: "a%20b%2Fc", chain: "urldecode", want: "a b/c"},
+		{name: "Chain", value: "/var/log/app.log", chain: "basename|upper|pad:10", want: "APP.LOG   "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mods, err := parseModifiers(tt.chain)
+			if err != nil {
+				t.Fatalf("parseModifiers(%q) error = %v", tt.chain, err)
+			}
+			if got := FormatValue(applyModifiers(tt.value, mods)); got != tt.want {
+				t.Errorf("applyModifiers(%v, %q) = %q, want %q", tt.value, tt.chain, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		wantErr bool
+	}{
+		{name: "No modifiers", format: "{timestamp} [{level}] {message}"},
+		{name: "Known modifiers", format: "{caller|basename|pad:20} {message|trunc:40}"},
+		{name: "Unknown modifier", format: "{level|shout}", wantErr: true},
+		{name: "Missing argument", format: "{message|trunc}", wantErr: true},
+		{name: "Bad argument", format: "{message|trunc:abc}", wantErr: true},
+		{name: "Unexpected argument", format: "{level|upper:1}", wantErr: true},
+		{name: "Unknown duration unit", format: "{latency|duration:h}", wantErr: true},
+		{name: "Replace needs two arguments", format: "{path|replace:/}", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateFormat(tt.format); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateFormat(%q) error = %v, wantErr %v", tt.format, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package logparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// OrderedJSON is a JSON object decoded together with the order of its keys
+type OrderedJSON struct {
+	Fields map[string]any
+	Keys   []string
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+func (o *OrderedJSON) UnmarshalJSON(data []byte) error {
+	// Decode the object token by token to see the keys in order
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	// Read the opening brace
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token != json.Delim('{') {
+		return fmt.Errorf("expected a JSON object")
+	}
+
+	o.Fields = make(map[string]any)
+	o.Keys = make([]string, 0)
+
+	// Read field names in order
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		key := token.(string)
+		var value any
+		if err := dec.Decode(&value); err != nil {
+			return err
+		}
+		// A repeated key keeps its first position and its last value
+		if _, seen := o.Fields[key]; !seen {
+			o.Keys = append(o.Keys, key)
+		}
+		o.Fields[key] = value
+	}
+
+	return nil
+}
+
+// fieldList is an ordered list of fields, as shown by {rest}
+type fieldList []fieldEntry
+
+type fieldEntry struct {
+	key   string
+	value any
+}
+
+// String renders the fields as key=value pairs, quoting text values that
+// contain spaces, quotes or equals signs
+func (l fieldList) String() string {
+	var b strings.Builder
+	for i, f := range l {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		value := FormatValue(f.value)
+		if _, isText := f.value.(string); isText && strings.ContainsAny(value, " \t\n\"=") {
+			value = strconv.Quote(value)
+		}
+		b.WriteString(f.key)
+		b.WriteByte('=')
+		b.WriteString(value)
+	}
+	return b.String()
+}
+
+// MarshalJSON renders the fields as a JSON object in their original order
+func (l fieldList) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, f := range l {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(encodeJSON(f.key))
+		b.WriteByte(':')
+		b.WriteString(encodeJSON(f.value))
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// orderedFields lists a record's top-level fields in the order they were
+// written, skipping the excluded keys. Keys whose order is not known, such
+// as those added by --jq, follow in sorted order.
+func (r *record) orderedFields(exclude map[string]bool) fieldList {
+	list := make(fieldList, 0, len(r.data))
+	listed := make(map[string]bool, len(r.keys))
+	for _, key := range r.keys {
+		if v, ok := r.data[key]; ok && !exclude[key] {
+			list = append(list, fieldEntry{key, v})
+		}
+		listed[key] = true
+	}
+
+	var unordered []string
+	for key := range r.data {
+		if !listed[key] && !exclude[key] {
+			unordered = append(unordered, key)
+		}
+	}
+	slices.Sort(unordered)
+	for _, key := range unordered {
+		list = append(list, fieldEntry{key, r.data[key]})
+	}
+	return list
+}
@@ -0,0 +1,101 @@
+package logparser
+
+import (
+	"bufio"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOrderedJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantKeys []string
+		wantErr  bool
+	}{
+		{
+			name:     "Keys in written order",
+			input:    `{"zeta": 1, "alpha": {"b": 2, "a": 1}, "mid": "x"}`,
+			wantKeys: []string{"zeta", "alpha", "mid"},
+		},
+		{
+			name:     "Repeated key keeps first position",
+			input:    `{"a": 1, "b": 2, "a": 3}`,
+			wantKeys: []string{"a", "b"},
+		},
+		{
+			name:    "Not an object",
+			input:   `[1, 2]`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var o OrderedJSON
+			err := json.Unmarshal([]byte(tt.input), &o)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(o.Keys, tt.wantKeys) {
+				t.Errorf("Keys = %v, want %v", o.Keys, tt.wantKeys)
+			}
+		})
+	}
+}
+
+func TestRenderRest(t *testing.T) {
+	line := `{"time": "2024-03-20T10:00:00Z", "level": "INFO", "msg": "started", "service": "api", "http": {"method": "GET"}, "note": "two words", "id": 12345678901234567890}`
+
+	tests := []struct {
+		name        string
+		format      string
+		hideMissing bool
+		want        string
+	}{
+		{
+			name:   "Remaining fields as key=value",
+			format: "{level} {message} {rest}",
+			want:   `INFO started time=2024-03-20T10:00:00Z service=api http={"method":"GET"} note="two words" id=12345678901234567890`,
+		},
+		{
+			name:   "Dots alias",
+			format: "{timestamp} {message} {...}",
+			want:   `2024-03-20T10:00:00Z started level=INFO service=api http={"method":"GET"} note="two words" id=12345678901234567890`,
+		},
+		{
+			name:   "JSON in original order",
+			format: "{message} {rest|json}",
+			want:   `started {"time":"2024-03-20T10:00:00Z","level":"INFO","service":"api","http":{"method":"GET"},"note":"two words","id":12345678901234567890}`,
+		},
+		{
+			name:   "Paths and sections consume their root key",
+			format: "{message} {http.method} [[{missing}]] {?note: !} {rest}",
+			want:   `started GET   ! time=2024-03-20T10:00:00Z level=INFO service=api id=12345678901234567890`,
+		},
+		{
+			name:        "Nothing left",
+			format:      "{time} {level} {msg} {service} {http} {note} {id} {rest}",
+			hideMissing: true,
+			want:        `2024-03-20T10:00:00Z INFO started api {"method":"GET"} two words 12345678901234567890`,
+		},
+		{
+			name:   "Nothing left inside optional section",
+			format: "{time} {level} {msg} {service} {http} {note} {id}[[ | {rest}]]",
+			want:   `2024-03-20T10:00:00Z INFO started api {"method":"GET"} two words 12345678901234567890`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := captureStdout(t, func() {
+				ProcessLog(bufio.NewScanner(strings.NewReader(line)), Options{Format: tt.format, HideMissing: tt.hideMissing})
+			})
+			if got := stripANSI(strings.TrimSuffix(out, "\n")); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,172 +2,202 @@ package logparser
 
 import (
 	"bufio"
-	"encoding/json"
+	"bytes"
+	"errors"
 	"fmt"
-	"path/filepath"
-	"regexp"
 	"slices"
 	"strings"
 	"time"
 
-	"github.com/fatih/color"
 	"github.com/techarm/jclog/internal/formatter"
 )
 
 // FieldAliases defines field name aliases for better flexibility
 var FieldAliases = map[string][]string{
 	"timestamp": {"timestamp", "time", "ts"},
-	"level":     {"level", "lvl", "severity"},
+	"level":     {"level", "lvl", "severity", "severityText", "SeverityText", "severityNumber", "SeverityNumber"},
 	"message":   {"message", "msg", "text"},
 }
 
-// Pattern for extracting field names from format string
-var fieldPattern = regexp.MustCompile(`{([^}]+)}`)
+// Options controls how ProcessLog filters and formats log entries
+type Options struct {
+	Format          string
+	GoTemplate      *GoTemplate
+	Fields          []string
+	MaxDepth        int
+	HideMissing     bool
+	Filters         []Filter
+	Excludes        []Filter
+	Where           *Where
+	JQ              *JQ
+	MinLevel        Level
+	MaxLevel        Level
+	Since           time.Time
+	Until           time.Time
+	KeepMissingTime bool
+	Grep            *Grep
+	BeforeContext   int
+	AfterContext    int
+	ContextField    string
+	Levels          *Levels
+	RawLevel        bool
+	Time            TimeSettings
+	GapThreshold    time.Duration
+}
 
 // ProcessLog parses JSON logs and outputs formatted results
-func ProcessLog(scanner *bufio.Scanner, format string, maxDepth int, hideMissing bool, filters map[string]string, excludes map[string]string, levelMappings map[string]string, autoConvertLevel bool, timeFormat string) {
-	// Get local timezone
-	localLoc := time.Local
-
-	// Extract fields from format string
-	fields := extractFields(format)
+func ProcessLog(scanner *bufio.Scanner, opts Options) {
+	// Let level filters match every spelling of their levels, and read
+	// timestamps in filters the way the records' timestamps are read
+	opts.Filters = prepareFilters(opts.Filters, opts.Levels, opts.Time)
+	opts.Excludes = prepareFilters(opts.Excludes, opts.Levels, opts.Time)
 
-	for scanner.Scan() {
-		// Parse the log line as JSON
-		raw := make(map[string]any)
-		if err := json.Unmarshal([]byte(scanner.Text()), &raw); err != nil {
-			fmt.Println("Invalid JSON:", scanner.Text())
-			continue
+	// Print a line, dimmed when it is context or else colored by its level
+	emit := func(output, level string, context bool) {
+		if context {
+			output = formatter.Dim(output)
+		} else if level != "" {
+			output = formatter.ColorizeByLevel(output, level)
 		}
+		fmt.Println(output)
+	}
 
-		// Extract fields
-		extractedFields := make(map[string]string)
-		for _, field := range fields {
-			// Check if field has a modifier
-			fieldName := field
-			modifier := ""
-			if strings.Contains(field, "|") {
-				parts := strings.Split(field, "|")
-				fieldName = parts[0]
-				if len(parts) > 1 {
-					modifier = parts[1]
-				}
-			}
-
-			value := getFieldValue(raw, fieldName)
-			// Apply level mappings if available
-			if fieldName == "level" && autoConvertLevel && levelMappings != nil {
-				if mapped, ok := levelMappings[value]; ok {
-					value = mapped
-				}
-			}
-			// Apply modifiers
-			if modifier == "basename" && fieldName == "file" {
-				value = filepath.Base(value)
-			}
-			// Format time fields with timezone conversion
-			if (fieldName == "time" || fieldName == "timestamp") && timeFormat != "" {
-				if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
-					// Convert to local timezone
-					t = t.In(localLoc)
-					value = t.Format(timeFormat)
-				} else {
-					// Try parsing other common formats
-					formats := []string{
-						time.RFC3339,
-						"2006-01-02T15:04:05Z",
-						"2006-01-02T15:04:05.000Z",
-						"2006-01-02 15:04:05",
-						"2006-01-02 15:04:05.000",
-					}
-					for _, f := range formats {
-						if t, err := time.Parse(f, value); err == nil {
-							// Convert to local timezone
-							t = t.In(localLoc)
-							value = t.Format(timeFormat)
-							break
-						}
-					}
-				}
-			}
-			extractedFields[field] = value
+	// Compile the format string or field list once; callers validate them
+	// with ValidateFormat and ValidateFields
+	var tmpl *formatTemplate
+	var renderer *formatRenderer
+	var columns *columnPrinter
+	var err error
+	switch {
+	case opts.GoTemplate != nil:
+	case len(opts.Fields) > 0:
+		if tmpl, err = parseFields(opts.Fields); err != nil {
+			fmt.Println(err)
+			return
 		}
+		columns = newColumnPrinter(tmpl, opts, emit)
+		defer columns.flush()
+	default:
+		if tmpl, err = parseFormat(opts.Format); err != nil {
+			fmt.Println(err)
+			return
+		}
+		renderer = newFormatRenderer(tmpl, opts)
+	}
 
-		// Handle nested message fields dynamically
-		if msg, exists := extractedFields["message"]; exists && msg != "" {
-			messageFields := make(map[string]string)
-			flattenJSONString(msg, "message", messageFields, maxDepth, 1)
-			for k, v := range messageFields {
-				if slices.Contains(fields, k) {
-					extractedFields[k] = v
-				}
+	printer := newContextPrinter(opts.BeforeContext, opts.AfterContext, opts.ContextField, func(rec *record, context bool) {
+		var output, level string
+		switch {
+		case opts.GoTemplate != nil:
+			// Go templates apply their own colors
+			var err error
+			if output, err = opts.GoTemplate.render(rec, !opts.RawLevel); err != nil {
+				fmt.Println("template error:", err)
+				return
 			}
+		case columns != nil:
+			// Columns print their rows once the widths settle
+			columns.add(rec, context)
+			return
+		default:
+			output, level = renderer.formatRecord(rec)
 		}
+		emit(output, level, context)
+	})
+	// Separators and errors wait with the rows held back by the columns
+	printLine := func(line string) { fmt.Println(line) }
+	if columns != nil {
+		printer.separate = columns.separate
+		printLine = columns.passthrough
+	}
 
-		// Apply filters (only show matching logs)
-		if len(filters) > 0 && !matchFilters(extractedFields, filters) {
-			continue
+	// Lines are decoded from the scanner's buffer; only invalid ones are copied
+	var line bytes.Reader
+	for scanner.Scan() {
+		// Parse the log line as JSON, keeping the key order when {rest} shows it
+		line.Reset(scanner.Bytes())
+		raw := make(map[string]any)
+		var keys []string
+		if tmpl != nil && tmpl.hasRest {
+			var ordered OrderedJSON
+			err = decodeJSONFrom(&line, &ordered)
+			raw, keys = ordered.Fields, ordered.Keys
+		} else {
+			err = decodeJSONFrom(&line, &raw)
 		}
-
-		// Apply excludes (hide matching logs)
-		if len(excludes) > 0 && matchFilters(extractedFields, excludes) {
+		if err != nil {
+			printLine("Invalid JSON: " + scanner.Text())
 			continue
 		}
 
-		// Format output with unknown field handling
-		output := format
-		for _, field := range fields {
-			value := extractedFields[field]
-			placeholder := "{" + field + "}"
-
-			if value == "" {
-				if hideMissing {
-					// Remove the placeholder and any surrounding brackets
-					output = removeFieldAndBrackets(output, field)
-				} else {
-					// Mark unknown field in gray with warning symbol
-					unknownValue := color.New(color.FgHiBlack).Sprintf("❓%s", field)
-					output = strings.Replace(output, placeholder, unknownValue, -1)
-				}
-			} else {
-				output = strings.Replace(output, placeholder, value, -1)
+		// Select and reshape the record with jq
+		entries := []map[string]any{raw}
+		if opts.JQ != nil {
+			var err error
+			if entries, err = opts.JQ.apply(raw); err != nil {
+				printLine(fmt.Sprint("jq error: ", err))
+				continue
 			}
 		}
 
-		// Apply color based on log level
-		if level, exists := extractedFields["level"]; exists {
-			output = formatter.ColorizeByLevel(output, level)
-		}
+		for _, data := range entries {
+			rec := newRecord(data, opts.MaxDepth)
+			rec.keys = keys
+			rec.timeField = opts.Time.Field
 
-		fmt.Println(output)
+			printer.add(rec, matchRecord(rec, opts))
+		}
 	}
 }
 
-// extractFields extracts field names from format string
-func extractFields(format string) []string {
-	matches := fieldPattern.FindAllStringSubmatch(format, -1)
-	fields := make([]string, 0, len(matches))
-	for _, match := range matches {
-		if len(match) > 1 {
-			fields = append(fields, match[1])
-		}
+// matchRecord applies the time window, level bounds, filters, excludes,
+// the where expression and the grep pattern to a record
+func matchRecord(rec *record, opts Options) bool {
+	// Apply time window
+	if (!opts.Since.IsZero() || !opts.Until.IsZero()) && !matchTimeWindow(rec, opts.Since, opts.Until, opts.KeepMissingTime, opts.Time) {
+		return false
+	}
+
+	// Apply severity bounds
+	if (opts.MinLevel != LevelUnknown || opts.MaxLevel != LevelUnknown) && !matchLevel(rec, opts.MinLevel, opts.MaxLevel, opts.Levels) {
+		return false
+	}
+
+	// Apply filters (only show matching logs)
+	if len(opts.Filters) > 0 && !matchFilters(rec, opts.Filters, opts.Levels) {
+		return false
+	}
+
+	// Apply excludes (hide matching logs)
+	if len(opts.Excludes) > 0 && matchFilters(rec, opts.Excludes, opts.Levels) {
+		return false
+	}
+
+	// Apply where expression
+	if opts.Where != nil && !opts.Where.match(rec, opts.Levels, opts.Time) {
+		return false
 	}
-	return fields
-}
 
-// removeFieldAndBrackets removes a field placeholder and its surrounding brackets
-func removeFieldAndBrackets(format, field string) string {
-	// Remove [field] pattern
-	bracketPattern := regexp.MustCompile(`\[[^]]*{` + field + `}[^]]*\]`)
-	format = bracketPattern.ReplaceAllString(format, "")
+	// Apply full-text search
+	if opts.Grep != nil && !opts.Grep.match(rec) {
+		return false
+	}
+	return true
+}
 
-	// Remove field pattern
-	fieldPattern := regexp.MustCompile(`{` + field + `}`)
-	format = fieldPattern.ReplaceAllString(format, "")
+// formatRecord renders a record with the compiled format and returns the
+// output and the level to color it by
+func (r *formatRenderer) formatRecord(rec *record) (string, string) {
+	output := r.render(rec)
+	if r.opts.HideMissing {
+		output = strings.TrimSpace(output)
+	}
 
-	// Clean up extra spaces
-	format = strings.ReplaceAll(format, "  ", " ")
-	return strings.TrimSpace(format)
+	level := ""
+	if r.tmpl.hasLevel {
+		level = rec.level(r.opts.Levels).String()
+	}
+	return output, level
 }
 
 // flattenJSONString tries to decode nested JSON strings recursively
@@ -201,70 +231,81 @@ func flattenJSONString(jsonStr string, prefix string, result map[string]string,
 			} else {
 				result[newKey] = v
 			}
-		case float64:
-			result[newKey] = fmt.Sprintf("%.0f", v)
-		case bool:
-			result[newKey] = fmt.Sprintf("%v", v)
 		case map[string]any:
 			if currentDepth < maxDepth {
 				for k, v := range v {
-					flattenJSONString(fmt.Sprintf("%v", v), newKey+"."+k, result, maxDepth, currentDepth+1)
+					flattenJSONString(FormatValue(v), newKey+"."+k, result, maxDepth, currentDepth+1)
 				}
 			} else {
-				jsonBytes, _ := json.Marshal(v)
-				result[newKey] = string(jsonBytes)
+				result[newKey] = FormatValue(v)
 			}
-		case nil:
-			result[newKey] = ""
 		default:
-			result[newKey] = fmt.Sprintf("%v", v)
+			result[newKey] = FormatValue(v)
 		}
 	}
 }
 
+var errNotObject = errors.New("not a JSON object")
+
+// looksLikeJSONObject reports whether a string starts like a JSON object
+func looksLikeJSONObject(s string) bool {
+	return strings.HasPrefix(strings.TrimLeft(s, " \t\r\n"), "{")
+}
+
 // tryParseJSON attempts to parse a JSON string and returns a map if successful
 func tryParseJSON(jsonStr string) (map[string]any, error) {
+	// Most messages are plain text; skip the decoder for them
+	if !looksLikeJSONObject(jsonStr) {
+		return nil, errNotObject
+	}
 	var parsed map[string]any
-	err := json.Unmarshal([]byte(jsonStr), &parsed)
+	err := decodeJSON(jsonStr, &parsed)
 	return parsed, err
 }
 
-// getFieldValue retrieves the first available value from field aliases
-func getFieldValue(data map[string]any, field string) string {
-	aliases, exists := FieldAliases[field]
-	if !exists {
-		aliases = []string{field} // No alias, just use the field name
-	}
-
-	for _, alias := range aliases {
-		if v, ok := data[alias].(string); ok {
-			return v
+// matchFilters checks the filter conditions against the record.
+// The = and =~ conditions on the same field, or on aliases of it, are
+// OR-ed; every other condition is AND-ed, so two bounds on a field form a
+// range and two negations exclude both values.
+// Level conditions also see the canonical name of the record's level.
+func matchFilters(rec *record, filters []Filter, levels *Levels) bool {
+	matched := make(map[string]bool, len(filters))
+	for _, filter := range filters {
+		field := canonicalField(filter.Field)
+		positive := filter.Operator == "=" || filter.Operator == "=~"
+		if positive && matched[field] {
+			continue
 		}
-		if v, ok := data[alias].(float64); ok {
-			if field == "level" {
-				return fmt.Sprintf("%d", int(v))
+		values, exists := rec.lookupStrings(filter.Field)
+		if exists && isLevelField(filter.Field) {
+			if level := rec.level(levels); level != LevelUnknown {
+				values = append(values, level.String())
 			}
-			return fmt.Sprintf("%.0f", v)
 		}
-		if v, ok := data[alias].(int); ok {
-			if field == "level" {
-				return fmt.Sprintf("%d", v)
+		ok := filter.MatchAny(values, exists)
+		if !positive {
+			if !ok {
+				return false
 			}
-			return fmt.Sprintf("%d", v)
+			continue
 		}
+		matched[field] = ok
 	}
-	return ""
-}
-
-// matchFilters checks if all filter conditions are met
-func matchFilters(fields map[string]string, filters map[string]string) bool {
-	if len(filters) == 0 {
-		return true
-	}
-	for key, expectedValue := range filters {
-		if actualValue, exists := fields[key]; !exists || actualValue != expectedValue {
+	for _, ok := range matched {
+		if !ok {
 			return false
 		}
 	}
 	return true
 }
+
+// canonicalField returns the name a field alias stands for, such as level
+// for lvl, or the field itself when it is not an alias
+func canonicalField(field string) string {
+	for name, aliases := range FieldAliases {
+		if slices.Contains(aliases, field) {
+			return name
+		}
+	}
+	return field
+}
@@ -11,92 +11,156 @@ import (
 
 func TestProcessLog(t *testing.T) {
 	tests := []struct {
-		name             string
-		input            string
-		format           string
-		maxDepth         int
-		filters          map[string]string
-		excludes         map[string]string
-		levelMappings    map[string]string
-		autoConvertLevel bool
-		timeFormat       string
-		wantOutput       bool
+		name          string
+		input         string
+		format        string
+		maxDepth      int
+		filters       []Filter
+		excludes      []Filter
+		where         string
+		jq            string
+		levelMappings map[string]string
+		timeFormat    string
+		wantOutput    bool
 	}{
 		{
-			name:             "Basic JSON log",
-			input:            `{"timestamp": "2024-03-20T10:00:00Z", "level": "INFO", "message": "test message"}`,
-			format:           "{timestamp} [{level}] {message}",
-			maxDepth:         2,
-			filters:          map[string]string{},
-			excludes:         map[string]string{},
-			levelMappings:    nil,
-			autoConvertLevel: false,
-			timeFormat:       "2006-01-02 15:04:05",
-			wantOutput:       true,
-		},
-		{
-			name:             "Invalid JSON",
-			input:            "invalid json",
-			format:           "{timestamp} [{level}] {message}",
-			maxDepth:         2,
-			filters:          map[string]string{},
-			excludes:         map[string]string{},
-			levelMappings:    nil,
-			autoConvertLevel: false,
-			timeFormat:       "2006-01-02 15:04:05",
-			wantOutput:       true, // will output error message
-		},
-		{
-			name:             "Log with filter",
-			input:            `{"timestamp": "2024-03-20T10:00:00Z", "level": "INFO", "message": "test message"}`,
-			format:           "{timestamp} [{level}] {message}",
-			maxDepth:         2,
-			filters:          map[string]string{"level": "INFO"},
-			excludes:         map[string]string{},
-			levelMappings:    nil,
-			autoConvertLevel: false,
-			timeFormat:       "2006-01-02 15:04:05",
-			wantOutput:       true,
-		},
-		{
-			name:             "Log with exclude",
-			input:            `{"timestamp": "2024-03-20T10:00:00Z", "level": "DEBUG", "message": "test message"}`,
-			format:           "{timestamp} [{level}] {message}",
-			maxDepth:         2,
-			filters:          map[string]string{},
-			excludes:         map[string]string{"level": "DEBUG"},
-			levelMappings:    nil,
-			autoConvertLevel: false,
-			timeFormat:       "2006-01-02 15:04:05",
-			wantOutput:       false,
-		},
-		{
-			name:             "Nested message",
-			input:            `{"timestamp": "2024-03-20T10:00:00Z", "level": "INFO", "message": "{\"nested\": \"value\"}"}`,
-			format:           "{timestamp} [{level}] {message.nested}",
-			maxDepth:         2,
-			filters:          map[string]string{},
-			excludes:         map[string]string{},
-			levelMappings:    nil,
-			autoConvertLevel: false,
-			timeFormat:       "2006-01-02 15:04:05",
-			wantOutput:       true,
+			name:          "Basic JSON log",
+			input:         `{"timestamp": "2024-03-20T10:00:00Z", "level": "INFO", "message": "test message"}`,
+			format:        "{timestamp} [{level}] {message}",
+			maxDepth:      2,
+			filters:       nil,
+			excludes:      nil,
+			levelMappings: nil,
+			timeFormat:    "2006-01-02 15:04:05",
+			wantOutput:    true,
+		},
+		{
+			name:          "Invalid JSON",
+			input:         "invalid json",
+			format:        "{timestamp} [{level}] {message}",
+			maxDepth:      2,
+			filters:       nil,
+			excludes:      nil,
+			levelMappings: nil,
+			timeFormat:    "2006-01-02 15:04:05",
+			wantOutput:    true, // will output error message
+		},
+		{
+			name:          "Log with filter",
+			input:         `{"timestamp": "2024-03-20T10:00:00Z", "level": "INFO", "message": "test message"}`,
+			format:        "{timestamp} [{level}] {message}",
+			maxDepth:      2,
+			filters:       []Filter{mustParseFilter(t, "level=INFO")},
+			excludes:      nil,
+			levelMappings: nil,
+			timeFormat:    "2006-01-02 15:04:05",
+			wantOutput:    true,
+		},
+		{
+			name:          "Log with exclude",
+			input:         `{"timestamp": "2024-03-20T10:00:00Z", "level": "DEBUG", "message": "test message"}`,
+			format:        "{timestamp} [{level}] {message}",
+			maxDepth:      2,
+			filters:       nil,
+			excludes:      []Filter{mustParseFilter(t, "level=DEBUG")},
+			levelMappings: nil,
+			timeFormat:    "2006-01-02 15:04:05",
+			wantOutput:    false,
+		},
+		{
+			name:          "Nested message",
+			input:         `{"timestamp": "2024-03-20T10:00:00Z", "level": "INFO", "message": "{\"nested\": \"value\"}"}`,
+			format:        "{timestamp} [{level}] {message.nested}",
+			maxDepth:      2,
+			filters:       nil,
+			excludes:      nil,
+			levelMappings: nil,
+			timeFormat:    "2006-01-02 15:04:05",
+			wantOutput:    true,
+		},
+		{
+			name:          "Filter on field not in format",
+			input:         `{"timestamp": "2024-03-20T10:00:00Z", "level": "INFO", "message": "paid", "service": "payment"}`,
+			format:        "{timestamp} [{level}] {message}",
+			maxDepth:      2,
+			filters:       []Filter{mustParseFilter(t, "service=payment")},
+			excludes:      nil,
+			levelMappings: nil,
+			timeFormat:    "2006-01-02 15:04:05",
+			wantOutput:    true,
+		},
+		{
+			name:          "Exclude on field not in format",
+			input:         `{"timestamp": "2024-03-20T10:00:00Z", "level": "INFO", "message": "ping", "uri": "/health"}`,
+			format:        "[{level}] {message}",
+			maxDepth:      2,
+			filters:       nil,
+			excludes:      []Filter{mustParseFilter(t, "uri=/health")},
+			levelMappings: nil,
+			timeFormat:    "2006-01-02 15:04:05",
+			wantOutput:    false,
+		},
+		{
+			name:          "Filter on mapped level",
+			input:         `{"time": "2024-03-20T10:00:00Z", "level": 50, "msg": "failed"}`,
+			format:        "{time} {msg}",
+			maxDepth:      2,
+			filters:       []Filter{mustParseFilter(t, "level=ERROR")},
+			excludes:      nil,
+			levelMappings: map[string]string{"50": "ERROR"},
+			timeFormat:    "2006-01-02 15:04:05",
+			wantOutput:    true,
+		},
+		{
+			name:       "Where expression match",
+			input:      `{"timestamp": "2024-03-20T10:00:00Z", "level": "WARN", "message": "slow", "http_code": 503}`,
+			format:     "{timestamp} [{level}] {message}",
+			maxDepth:   2,
+			where:      `level == "ERROR" || http_code >= 500`,
+			timeFormat: "2006-01-02 15:04:05",
+			wantOutput: true,
+		},
+		{
+			name:       "Where expression no match",
+			input:      `{"timestamp": "2024-03-20T10:00:00Z", "level": "INFO", "message": "ping", "uri": "/health"}`,
+			format:     "{timestamp} [{level}] {message}",
+			maxDepth:   2,
+			where:      `!(uri startsWith "/health")`,
+			timeFormat: "2006-01-02 15:04:05",
+			wantOutput: false,
+		},
+		{
+			name:       "jq select drops record",
+			input:      `{"timestamp": "2024-03-20T10:00:00Z", "level": "INFO", "message": "ok", "status": 200}`,
+			format:     "{timestamp} [{level}] {message}",
+			maxDepth:   2,
+			jq:         `select(.status >= 500)`,
+			timeFormat: "2006-01-02 15:04:05",
+			wantOutput: false,
+		},
+		{
+			name:       "jq object replaces record",
+			input:      `{"timestamp": "2024-03-20T10:00:00Z", "level": "INFO", "message": "ok", "status": 200}`,
+			format:     "{status} {message}",
+			maxDepth:   2,
+			jq:         `{message, status}`,
+			timeFormat: "2006-01-02 15:04:05",
+			wantOutput: true,
 		},
 		{
 			name:     "Bunyan log with level mapping",
 			input:    `{"time": "2024-03-20T10:00:00Z", "level": 30, "msg": "test message"}`,
 			format:   "{time} [{level}] {msg}",
 			maxDepth: 2,
-			filters:  map[string]string{},
-			excludes: map[string]string{},
+			filters:  nil,
+			excludes: nil,
 			levelMappings: map[string]string{
 				"30": "INFO",
 				"40": "WARN",
 				"50": "ERROR",
 			},
-			autoConvertLevel: true,
-			timeFormat:       "2006-01-02 15:04:05",
-			wantOutput:       true,
+			timeFormat: "2006-01-02 15:04:05",
+			wantOutput: true,
 		},
 	}
 
@@ -118,7 +182,33 @@ func TestProcessLog(t *testing.T) {
 			// Process log
 			reader := strings.NewReader(tt.input)
 			scanner := bufio.NewScanner(reader)
-			ProcessLog(scanner, tt.format, tt.maxDepth, false, tt.filters, tt.excludes, tt.levelMappings, tt.autoConvertLevel, tt.timeFormat)
+			levels, err := NewLevels(tt.levelMappings, nil)
+			if err != nil {
+				t.Fatalf("NewLevels() error = %v", err)
+			}
+			opts := Options{
+				Format:   tt.format,
+				MaxDepth: tt.maxDepth,
+				Filters:  tt.filters,
+				Excludes: tt.excludes,
+				Levels:   levels,
+				Time:     TimeSettings{Format: tt.timeFormat},
+			}
+			if tt.where != "" {
+				where, err := ParseWhere(tt.where)
+				if err != nil {
+					t.Fatalf("ParseWhere() error = %v", err)
+				}
+				opts.Where = where
+			}
+			if tt.jq != "" {
+				jq, err := ParseJQ(tt.jq)
+				if err != nil {
+					t.Fatalf("ParseJQ() error = %v", err)
+				}
+				opts.JQ = jq
+			}
+			ProcessLog(scanner, opts)
 
 			// Close write end of pipe and read output
 			w.Close()
@@ -136,6 +226,77 @@ func TestProcessLog(t *testing.T) {
 	}
 }
 
+func TestFormatRecord(t *testing.T) {
+	data := map[string]any{
+		"level":   "INFO",
+		"msg":     `{"user": "alice"}`,
+		"service": "api",
+		"http": map[string]any{
+			"request": map[string]any{"method": "GET"},
+		},
+		"tags": []any{"api", "v2"},
+		"note": "saw {level} and {{x}}",
+		"errors": []any{
+			map[string]any{"code": "E1"},
+			map[string]any{"code": "E2"},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		format      string
+		hideMissing bool
+		want        string
+	}{
+		{name: "Plain field", format: "[{level}]", want: "[INFO]"},
+		{name: "Nested path", format: "{http.request.method}", want: "GET"},
+		{name: "Array index", format: "{tags[0]} {tags[-1]}", want: "api v2"},
+		{name: "Wildcard", format: "{errors[*].code}", want: `["E1","E2"]`},
+		{name: "Message JSON field", format: "{message.user}", want: "alice"},
+		{name: "Modifier chain", format: "[{level|lower|pad:6}]", want: "[info  ]"},
+		{name: "Modifier on path", format: "{http.request.method|lower}", want: "get"},
+		{name: "Default for missing field", format: "{user|default:anonymous}", want: "anonymous"},
+		{name: "Missing field marker", format: "[{level}] {user}", want: "[INFO] ❓user"},
+		{name: "Missing field marker keeps pad", format: "{user|basename|pad:8}| {level}", want: "❓user  | INFO"},
+		{name: "Missing field marker keeps spec", format: "{user:>8}|", want: "  ❓user|"},
+		{name: "Hide missing drops the word", format: "[{level}] (user={user}) {service}", hideMissing: true, want: "[INFO] api"},
+		{name: "Hide missing at start", format: "{user} [{level}]", hideMissing: true, want: "[INFO]"},
+		{name: "Hide missing keeps partly present word", format: "{service}/{user} ok", hideMissing: true, want: "api/ ok"},
+		{name: "Modifier skips missing field", format: "[{user|upper}]", hideMissing: true, want: ""},
+		{name: "Fallback", format: "user={user:-anonymous}", want: "user=anonymous"},
+		{name: "Fallback unused", format: "svc={service:-none}", want: "svc=api"},
+		{name: "Fallback keeps colons", format: "{user:-n/a: none}", want: "n/a: none"},
+		{name: "Conditional section present", format: "[{level}]{?service: service={service}}", want: "[INFO] service=api"},
+		{name: "Conditional section absent", format: "[{level}]{?error: err={error}} done", want: "[INFO] done"},
+		{name: "Conditional section needs every field", format: "{?service: {service}/{user}}!", want: "!"},
+		{name: "Nested conditional sections", format: "{?service: s={service}{?level: l={level}}}", want: " s=api l=INFO"},
+		{name: "Nested conditional section absent", format: "{?service: s={service}{?user: u={user}}}!", want: " s=api!"},
+		{name: "Brace before conditional section close", format: "{?service: [ok]}}", want: " [ok]}"},
+		{name: "Escaped braces in conditional section", format: "{?service: {{{service}}}}", want: " {api}"},
+		{name: "Optional section present", format: "{level}[[ svc={service}]]", want: "INFO svc=api"},
+		{name: "Optional section absent", format: "{level}[[ user={user}]] end", want: "INFO end"},
+		{name: "Nested sections", format: "{level}[[ ({service}[[ as {user}]])]]", want: "INFO (api)"},
+		{name: "Section with fallback", format: "[[user={user:-anonymous}]]", want: "user=anonymous"},
+		{name: "Escaped braces", format: "{{{level}}} {{}}", want: "{INFO} {}"},
+		{name: "Escaped braces in section", format: "{?service: {{{service}}}}", want: " {api}"},
+		{name: "Braces in values are not placeholders", format: "[{level}] {note}", want: "[INFO] saw {level} and {{x}}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := parseFormat(tt.format)
+			if err != nil {
+				t.Fatalf("parseFormat() error = %v", err)
+			}
+			opts := Options{Format: tt.format, HideMissing: tt.hideMissing}
+			got, _ := newFormatRenderer(tmpl, opts).formatRecord(newRecord(data, 2))
+			if got = stripANSI(got); got != tt.want {
+				t.Errorf("formatRecord() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestFlattenJSONString(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -200,108 +361,176 @@ func TestFlattenJSONString(t *testing.T) {
 	}
 }
 
-func TestGetFieldValue(t *testing.T) {
+func TestMatchFilters(t *testing.T) {
 	tests := []struct {
-		name      string
-		data      map[string]any
-		field     string
-		wantValue string
+		name    string
+		data    map[string]any
+		filters []string
+		want    bool
 	}{
 		{
-			name: "Direct field",
+			name: "Exact match",
+			data: map[string]any{
+				"level": "INFO",
+				"env":   "prod",
+			},
+			filters: []string{"level=INFO"},
+			want:    true,
+		},
+		{
+			name: "No match",
+			data: map[string]any{
+				"level": "DEBUG",
+			},
+			filters: []string{"level=INFO"},
+			want:    false,
+		},
+		{
+			name: "Field does not exist",
+			data: map[string]any{
+				"level": "INFO",
+			},
+			filters: []string{"nonexistent=value"},
+			want:    false,
+		},
+		{
+			name: "Empty filter",
+			data: map[string]any{
+				"level": "INFO",
+			},
+			filters: []string{},
+			want:    true,
+		},
+		{
+			name: "All conditions must match",
+			data: map[string]any{
+				"level":       "ERROR",
+				"duration_ms": float64(1500),
+			},
+			filters: []string{"level=ERROR", "duration_ms>2000"},
+			want:    false,
+		},
+		{
+			name: "Repeated field is OR",
+			data: map[string]any{
+				"level": "WARN",
+			},
+			filters: []string{"level=ERROR", "level=WARN"},
+			want:    true,
+		},
+		{
+			name: "Repeated field with different field is AND",
 			data: map[string]any{
-				"test": "value",
+				"level":   "WARN",
+				"service": "auth",
 			},
-			field:     "test",
-			wantValue: "value",
+			filters: []string{"level=ERROR", "service=auth", "level=WARN", "service=payment"},
+			want:    true,
+		},
+		{
+			name: "Different fields must all match",
+			data: map[string]any{
+				"level":   "WARN",
+				"service": "auth",
+			},
+			filters: []string{"level=ERROR", "level=WARN", "service=payment"},
+			want:    false,
 		},
 		{
 			name: "Alias field",
 			data: map[string]any{
-				"msg": "message value",
+				"lvl": "WARN",
 			},
-			field:     "message",
-			wantValue: "message value",
+			filters: []string{"level=WARN"},
+			want:    true,
 		},
 		{
-			name: "Numeric field",
+			name: "Nested path",
 			data: map[string]any{
-				"number": float64(123),
+				"http": map[string]any{"status": float64(503)},
 			},
-			field:     "number",
-			wantValue: "123",
+			filters: []string{"http.status>=500"},
+			want:    true,
 		},
 		{
-			name: "Non-existent field",
+			name: "Array index",
 			data: map[string]any{
-				"exists": "value",
+				"tags": []any{"api", "v2"},
 			},
-			field:     "nonexistent",
-			wantValue: "",
+			filters: []string{"tags[1]=v2"},
+			want:    true,
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got := getFieldValue(tt.data, tt.field)
-			if got != tt.wantValue {
-				t.Errorf("getFieldValue() = %v, want %v", got, tt.wantValue)
-			}
-		})
-	}
-}
-
-func TestMatchFilters(t *testing.T) {
-	tests := []struct {
-		name    string
-		fields  map[string]string
-		filters map[string]string
-		want    bool
-	}{
 		{
-			name: "Exact match",
-			fields: map[string]string{
-				"level": "INFO",
-				"env":   "prod",
+			name: "Wildcard matches any element",
+			data: map[string]any{
+				"errors": []any{
+					map[string]any{"code": "E1"},
+					map[string]any{"code": "E2"},
+				},
 			},
-			filters: map[string]string{
-				"level": "INFO",
+			filters: []string{"errors[*].code=E2"},
+			want:    true,
+		},
+		{
+			name: "Wildcard not equal requires every element",
+			data: map[string]any{
+				"errors": []any{
+					map[string]any{"code": "E1"},
+					map[string]any{"code": "E2"},
+				},
 			},
-			want: true,
+			filters: []string{"errors[*].code!=E2"},
+			want:    false,
 		},
 		{
-			name: "No match",
-			fields: map[string]string{
-				"level": "DEBUG",
+			name: "JSON inside message",
+			data: map[string]any{
+				"msg": `{"user": "alice"}`,
 			},
-			filters: map[string]string{
-				"level": "INFO",
+			filters: []string{"message.user=alice"},
+			want:    true,
+		},
+		{
+			name: "Range on one field",
+			data: map[string]any{
+				"duration_ms": float64(800),
 			},
-			want: false,
+			filters: []string{"duration_ms>100", "duration_ms<500"},
+			want:    false,
 		},
 		{
-			name: "Field does not exist",
-			fields: map[string]string{
-				"level": "INFO",
+			name: "Value inside range on one field",
+			data: map[string]any{
+				"duration_ms": float64(250),
 			},
-			filters: map[string]string{
-				"nonexistent": "value",
+			filters: []string{"duration_ms>100", "duration_ms<500"},
+			want:    true,
+		},
+		{
+			name: "Two negations on one field",
+			data: map[string]any{
+				"level": "ERROR",
 			},
-			want: false,
+			filters: []string{"level!=INFO", "level!=ERROR"},
+			want:    false,
 		},
 		{
-			name: "Empty filter",
-			fields: map[string]string{
-				"level": "INFO",
+			name: "Aliases are OR-ed",
+			data: map[string]any{
+				"lvl": "WARN",
 			},
-			filters: map[string]string{},
+			filters: []string{"level=ERROR", "lvl=WARN"},
 			want:    true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := matchFilters(tt.fields, tt.filters); got != tt.want {
+			filters := make([]Filter, 0, len(tt.filters))
+			for _, f := range tt.filters {
+				filters = append(filters, mustParseFilter(t, f))
+			}
+			if got := matchFilters(newRecord(tt.data, 2), filters, nil); got != tt.want {
 				t.Errorf("matchFilters() = %v, want %v", got, tt.want)
 			}
 		})
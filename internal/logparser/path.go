@@ -0,0 +1,194 @@
+package logparser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// pathSegment is one step of a field path: an object key, an array index or [*]
+type pathSegment struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// Parsed paths by source text, shared across records
+var pathCache sync.Map
+
+// isFieldPath reports whether a field name uses path syntax (dots, brackets or quotes)
+func isFieldPath(field string) bool {
+	return strings.ContainsAny(field, `.["`)
+}
+
+// parsePath parses a field path such as http.request.method, tags[0],
+// errors[*].code or "k8s.pod".name
+func parsePath(path string) ([]pathSegment, error) {
+	if cached, ok := pathCache.Load(path); ok {
+		return cached.([]pathSegment), nil
+	}
+
+	var segments []pathSegment
+	for i := 0; i < len(path); {
+		switch {
+		case path[i] == '"':
+			key, n, err := readQuotedKey(path[i:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid field path %q: %v", path, err)
+			}
+			segments = append(segments, pathSegment{key: key})
+			i += n
+		case path[i] == '[':
+			// A ] inside a quoted key does not close the brackets
+			_, end, ok := cutUnquoted(path[i:], ']')
+			if !ok {
+				return nil, fmt.Errorf("invalid field path %q: missing ]", path)
+			}
+			inner := path[i+1 : i+end]
+			switch {
+			case inner == "*":
+				segments = append(segments, pathSegment{wildcard: true})
+			case strings.HasPrefix(inner, `"`):
+				key, n, err := readQuotedKey(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid field path %q: %v", path, err)
+				}
+				if n != len(inner) {
+					return nil, fmt.Errorf("invalid field path %q: unexpected %q after quoted key", path, inner[n:])
+				}
+				segments = append(segments, pathSegment{key: key})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid field path %q: bad index %q", path, inner)
+				}
+				segments = append(segments, pathSegment{index: index, isIndex: true})
+			}
+			i += end + 1
+		case path[i] == '.':
+			if i == 0 || i == len(path)-1 {
+				return nil, fmt.Errorf("invalid field path %q: empty key", path)
+			}
+			i++
+		default:
+			end := strings.IndexAny(path[i:], ".[")
+			if end < 0 {
+				end = len(path) - i
+			}
+			segments = append(segments, pathSegment{key: path[i : i+end]})
+			i += end
+		}
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("invalid field path %q: empty path", path)
+	}
+
+	pathCache.Store(path, segments)
+	return segments, nil
+}
+
+// readQuotedKey reads a double-quoted key at the start of s and returns
+// the key and the number of bytes consumed
+func readQuotedKey(s string) (string, int, error) {
+	for end := 1; end < len(s); end++ {
+		switch s[end] {
+		case '\\':
+			end++
+		case '"':
+			key, err := strconv.Unquote(s[:end+1])
+			return key, end + 1, err
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated quoted key")
+}
+
+// hasWildcard reports whether the path contains a [*] segment
+func hasWildcard(segments []pathSegment) bool {
+	for _, seg := range segments {
+		if seg.wildcard {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePath walks the path through nested objects and arrays. A [*]
+// segment applies the rest of the path to every element and collects the
+// results into an array.
+func resolvePath(v any, segments []pathSegment) (any, bool) {
+	for i, seg := range segments {
+		switch {
+		case seg.wildcard:
+			arr, ok := v.([]any)
+			if !ok {
+				return nil, false
+			}
+			results := make([]any, 0, len(arr))
+			for _, item := range arr {
+				if r, ok := resolvePath(item, segments[i+1:]); ok {
+					results = append(results, r)
+				}
+			}
+			return results, len(results) > 0
+		case seg.isIndex:
+			arr, ok := v.([]any)
+			if !ok {
+				return nil, false
+			}
+			index := seg.index
+			if index < 0 {
+				index += len(arr)
+			}
+			if index < 0 || index >= len(arr) {
+				return nil, false
+			}
+			v = arr[index]
+		default:
+			obj, ok := v.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			if v, ok = obj[seg.key]; !ok {
+				return nil, false
+			}
+		}
+		if v == nil {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// JoinPath appends an object key to a field path, quoting keys that
+// contain path syntax
+func JoinPath(prefix, key string) string {
+	if strings.ContainsAny(key, `.[]"`) || key == "" {
+		key = strconv.Quote(key)
+	}
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// LookupPath resolves a field name or path against decoded JSON data,
+// including field aliases for the first path segment
+func LookupPath(data map[string]any, field string) (any, bool) {
+	if v, ok := lookupAlias(data, field); ok {
+		return v, true
+	}
+	if !isFieldPath(field) {
+		return nil, false
+	}
+	segments, err := parsePath(field)
+	if err != nil || segments[0].isIndex || segments[0].wildcard {
+		return nil, false
+	}
+	root, ok := lookupAlias(data, segments[0].key)
+	if !ok {
+		return nil, false
+	}
+	return resolvePath(root, segments[1:])
+}
@@ -0,0 +1,138 @@
+package logparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		want    []pathSegment
+		wantErr bool
+	}{
+		{
+			name: "Dotted keys",
+			path: "http.request.method",
+			want: []pathSegment{{key: "http"}, {key: "request"}, {key: "method"}},
+		},
+		{
+			name: "Array index",
+			path: "tags[0]",
+			want: []pathSegment{{key: "tags"}, {index: 0, isIndex: true}},
+		},
+		{
+			name: "Negative index",
+			path: "tags[-1]",
+			want: []pathSegment{{key: "tags"}, {index: -1, isIndex: true}},
+		},
+		{
+			name: "Wildcard",
+			path: "errors[*].code",
+			want: []pathSegment{{key: "errors"}, {wildcard: true}, {key: "code"}},
+		},
+		{
+			name: "Quoted key",
+			path: `"k8s.pod".name`,
+			want: []pathSegment{{key: "k8s.pod"}, {key: "name"}},
+		},
+		{
+			name: "Bracketed quoted key",
+			path: `labels["app.kubernetes.io/name"]`,
+			want: []pathSegment{{key: "labels"}, {key: "app.kubernetes.io/name"}},
+		},
+		{
+			name: "Bracketed quoted key with bracket",
+			path: `labels["a]b"].c`,
+			want: []pathSegment{{key: "labels"}, {key: "a]b"}, {key: "c"}},
+		},
+		{
+			name: "Bracketed key only",
+			path: `["a]b"]`,
+			want: []pathSegment{{key: "a]b"}},
+		},
+		{name: "Missing bracket", path: "tags[0", wantErr: true},
+		{name: "Unterminated bracketed key", path: `labels["a]`, wantErr: true},
+		{name: "Text after bracketed key", path: `labels["a"b]`, wantErr: true},
+		{name: "Bad index", path: "tags[x]", wantErr: true},
+		{name: "Trailing dot", path: "http.", wantErr: true},
+		{name: "Unterminated quote", path: `"k8s.pod`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePath(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePath(%q) = %+v, want %+v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupPath(t *testing.T) {
+	data := map[string]any{
+		"lvl": "INFO",
+		"http": map[string]any{
+			"request": map[string]any{"method": "GET"},
+		},
+		"tags": []any{"api", "v2", "public"},
+		"errors": []any{
+			map[string]any{"code": "E1"},
+			map[string]any{"code": "E2"},
+			map[string]any{"detail": "no code"},
+		},
+		"k8s.pod": map[string]any{"name": "web-1"},
+		"a]b":     "bracket",
+	}
+
+	tests := []struct {
+		name       string
+		field      string
+		want       any
+		wantExists bool
+	}{
+		{name: "Plain field", field: "lvl", want: "INFO", wantExists: true},
+		{name: "Alias field", field: "level", want: "INFO", wantExists: true},
+		{name: "Nested object", field: "http.request.method", want: "GET", wantExists: true},
+		{name: "Array index", field: "tags[0]", want: "api", wantExists: true},
+		{name: "Negative index", field: "tags[-1]", want: "public", wantExists: true},
+		{name: "Index out of range", field: "tags[3]", wantExists: false},
+		{name: "Wildcard collects values", field: "errors[*].code", want: []any{"E1", "E2"}, wantExists: true},
+		{name: "Quoted key with dots", field: `"k8s.pod".name`, want: "web-1", wantExists: true},
+		{name: "Bracketed key with bracket", field: `["a]b"]`, want: "bracket", wantExists: true},
+		{name: "Key on non-object", field: "tags.name", wantExists: false},
+		{name: "Missing field", field: "http.response.status", wantExists: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, exists := LookupPath(data, tt.field)
+			if exists != tt.wantExists || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LookupPath(%q) = %v, %v, want %v, %v", tt.field, got, exists, tt.want, tt.wantExists)
+			}
+		})
+	}
+}
+
+func TestJoinPath(t *testing.T) {
+	tests := []struct {
+		prefix string
+		key    string
+		want   string
+	}{
+		{prefix: "", key: "http", want: "http"},
+		{prefix: "http", key: "method", want: "http.method"},
+		{prefix: "", key: "k8s.pod", want: `"k8s.pod"`},
+		{prefix: "errors[*]", key: "code", want: "errors[*].code"},
+	}
+
+	for _, tt := range tests {
+		if got := JoinPath(tt.prefix, tt.key); got != tt.want {
+			t.Errorf("JoinPath(%q, %q) = %q, want %q", tt.prefix, tt.key, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package logparser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/techarm/jclog/internal/formatter"
+)
+
+// placeholder is a parsed {field:spec|modifier|...} or {field:-fallback}
+// reference in a format
+type placeholder struct {
+	field string
+	spec  *formatSpec
+	mods  []modifier
+	// relative shows a timestamp relative to other records or the current
+	// time, before the other modifiers run
+	relative relativeMode
+	// fallback is shown instead of a missing value
+	fallback    string
+	hasFallback bool
+}
+
+// formatSpec is a printf-style layout such as <5, >3, .2f or 60
+type formatSpec struct {
+	// align is '<', '>' or '^'; zero aligns numbers right and text left
+	align     byte
+	width     int
+	precision int // -1 when not set
+}
+
+// restField is the placeholder for every field the format does not otherwise
+// show; {...} is an alias
+const restField = "rest"
+
+var formatSpecPattern = regexp.MustCompile(`^([<>^])?(\d+)?(?:\.(\d+)f?)?$`)
+
+// Parsed placeholders by source text, shared across records
+var placeholderCache sync.Map
+
+// parsePlaceholder parses the text between the braces of a placeholder.
+// The field name ends at the first : (format spec, or :- fallback text) or
+// | (modifiers) outside a quoted key; the spec is applied after the modifiers.
+func parsePlaceholder(text string) (placeholder, error) {
+	if cached, ok := placeholderCache.Load(text); ok {
+		return cached.(placeholder), nil
+	}
+
+	end := len(text)
+	inQuote := false
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == '\\' && inQuote:
+			i++
+		case c == '"':
+			inQuote = !inQuote
+		case (c == ':' || c == '|') && !inQuote:
+			end = i
+		}
+		if end < len(text) {
+			break
+		}
+	}
+
+	p := placeholder{field: text[:end]}
+	switch {
+	case p.field == "":
+		return placeholder{}, fmt.Errorf("missing field name")
+	case p.field == "...":
+		p.field = restField
+	case isFieldPath(p.field):
+		if _, err := parsePath(p.field); err != nil {
+			return placeholder{}, err
+		}
+	}
+	rest := text[end:]
+	if fallback, ok := strings.CutPrefix(rest, ":-"); ok {
+		p.fallback, p.hasFallback = fallback, true
+		rest = ""
+	}
+	if strings.HasPrefix(rest, ":") {
+		specText, chain, _ := strings.Cut(rest[1:], "|")
+		spec, err := parseFormatSpec(specText)
+		if err != nil {
+			return placeholder{}, err
+		}
+		p.spec = &spec
+		rest = "|" + chain
+	}
+	if chain := strings.TrimPrefix(rest, "|"); chain != "" {
+		name, next, _ := strings.Cut(chain, "|")
+		if mode, ok := relativeModes[name]; ok {
+			p.relative = mode
+			chain = next
+		}
+		mods, err := parseModifiers(chain)
+		if err != nil {
+			return placeholder{}, err
+		}
+		p.mods = mods
+	}
+
+	placeholderCache.Store(text, p)
+	return p, nil
+}
+
+// parseFormatSpec parses [align][width][.precision[f]]
+func parseFormatSpec(text string) (formatSpec, error) {
+	m := formatSpecPattern.FindStringSubmatch(text)
+	if m == nil || text == "" {
+		return formatSpec{}, fmt.Errorf("invalid format spec %q (expected [<>^][width][.precision])", text)
+	}
+	spec := formatSpec{precision: -1}
+	if m[1] != "" {
+		spec.align = m[1][0]
+	}
+	if m[2] != "" {
+		spec.width, _ = strconv.Atoi(m[2])
+	}
+	if m[3] != "" {
+		spec.precision, _ = strconv.Atoi(m[3])
+	}
+	return spec, nil
+}
+
+// apply renders a value with the spec: numbers are rounded to the
+// precision, then text is padded to the width, or cut with an ellipsis
+// when it is wider
+func (s formatSpec) apply(v any) string {
+	text := FormatValue(v)
+	if s.precision >= 0 {
+		if n, ok := floatArg(v); ok {
+			text = strconv.FormatFloat(n, 'f', s.precision, 64)
+		}
+	}
+	if s.width == 0 {
+		return text
+	}
+
+	align := s.align
+	if align == 0 {
+		align = '<'
+		if _, ok := numberValue(v); ok {
+			align = '>'
+		}
+	}
+	return formatter.PadWidth(formatter.TruncateWidth(text, s.width), s.width, align)
+}
+
+// render applies the modifiers and format spec to a looked-up value and
+// reports whether the result is present
+func (p placeholder) render(v any) (string, bool) {
+	v = applyModifiers(v, p.mods)
+	if FormatValue(v) == "" {
+		return p.fallback, p.hasFallback
+	}
+	if p.spec != nil {
+		return p.spec.apply(v), true
+	}
+	return FormatValue(v), true
+}
+
+// missing lays out the marker shown for a missing value with the trailing
+// pad modifiers and the format spec, so the marker keeps the column width
+func (p placeholder) missing(marker string) string {
+	i := len(p.mods)
+	for i > 0 && p.mods[i-1].name == "pad" {
+		i--
+	}
+	text := FormatValue(applyModifiers(marker, p.mods[i:]))
+	if p.spec != nil {
+		text = p.spec.apply(text)
+	}
+	return text
+}
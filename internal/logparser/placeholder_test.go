@@ -0,0 +1,138 @@
+package logparser
+
+import (
+	"bufio"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestParsePlaceholder(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		wantField string
+		wantSpec  *formatSpec
+		wantMods  int
+		wantErr   bool
+	}{
+		{name: "Field only", text: "level", wantField: "level"},
+		{name: "Modifiers", text: "caller|basename|pad:20", wantField: "caller", wantMods: 2},
+		{name: "Left aligned", text: "level:<5", wantField: "level", wantSpec: &formatSpec{align: '<', width: 5, precision: -1}},
+		{name: "Right aligned", text: "http_code:>3", wantField: "http_code", wantSpec: &formatSpec{align: '>', width: 3, precision: -1}},
+		{name: "Precision", text: "latency_ms:.2f", wantField: "latency_ms", wantSpec: &formatSpec{precision: 2}},
+		{name: "Width and precision", text: "latency_ms:8.1", wantField: "latency_ms", wantSpec: &formatSpec{width: 8, precision: 1}},
+		{name: "Spec and modifiers", text: "caller:^20|basename", wantField: "caller", wantSpec: &formatSpec{align: '^', width: 20, precision: -1}, wantMods: 1},
+		{name: "Path", text: "http.request.method:<6", wantField: "http.request.method", wantSpec: &formatSpec{align: '<', width: 6, precision: -1}},
+		{name: "Quoted key with colon", text: `"a:b".c:4`, wantField: `"a:b".c`, wantSpec: &formatSpec{width: 4, precision: -1}},
+		{name: "Invalid spec", text: "level:wide", wantErr: true},
+		{name: "Empty spec", text: "level:", wantErr: true},
+		{name: "Unknown modifier", text: "level:5|shout", wantErr: true},
+		{name: "Rest alias", text: "...|pad:3", wantField: restField, wantMods: 1},
+		{name: "Empty field", text: "", wantErr: true},
+		{name: "Empty field with modifier", text: "|upper", wantErr: true},
+		{name: "Empty field with spec", text: ":5", wantErr: true},
+		{name: "Unterminated index", text: "a[", wantErr: true},
+		{name: "Bad index", text: "tags[x]", wantErr: true},
+		{name: "Unterminated quoted key", text: `"k8s.pod`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePlaceholder(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePlaceholder(%q) error = %v, wantErr %v", tt.text, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.field != tt.wantField || len(got.mods) != tt.wantMods {
+				t.Errorf("parsePlaceholder(%q) = field %q with %d modifiers, want %q with %d", tt.text, got.field, len(got.mods), tt.wantField, tt.wantMods)
+			}
+			if (got.spec == nil) != (tt.wantSpec == nil) || (got.spec != nil && *got.spec != *tt.wantSpec) {
+				t.Errorf("parsePlaceholder(%q) spec = %+v, want %+v", tt.text, got.spec, tt.wantSpec)
+			}
+		})
+	}
+}
+
+func TestFormatSpecApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		spec  string
+		value any
+		want  string
+	}{
+		{name: "Pad text left", spec: "<5", value: "INFO", want: "INFO "},
+		{name: "Pad number right by default", spec: "5", value: json.Number("200"), want: "  200"},
+		{name: "Pad text left by default", spec: "5", value: "200", want: "200  "},
+		{name: "Right aligned", spec: ">3", value: json.Number("7"), want: "  7"},
+		{name: "Centered", spec: "^6", value: "ok", want: "  ok  "},
+		{name: "Precision", spec: ".2f", value: json.Number("12.3456"), want: "12.35"},
+		{name: "Precision pads zeros", spec: ".2", value: json.Number("3"), want: "3.00"},
+		{name: "Precision on numeric string", spec: ".1f", value: "0.26", want: "0.3"},
+		{name: "Precision ignores text", spec: ".2f", value: "n/a", want: "n/a"},
+		{name: "Width and precision", spec: "8.2f", value: 1.5, want: "    1.50"},
+		{name: "Truncate with ellipsis", spec: "10", value: "connection refused", want: "connectio…"},
+		{name: "Truncate CJK", spec: "7", value: "日本語のログ", want: "日本語…"},
+		{name: "Pad CJK", spec: "<8", value: "完了", want: "完了    "},
+		{name: "Pad emoji", spec: "<4", value: "🔥", want: "🔥  "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := parseFormatSpec(tt.spec)
+			if err != nil {
+				t.Fatalf("parseFormatSpec(%q) error = %v", tt.spec, err)
+			}
+			if got := spec.apply(tt.value); got != tt.want {
+				t.Errorf("apply(%v) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatSpecWithLevelColors(t *testing.T) {
+	oldNoColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = oldNoColor }()
+
+	input := `{"level": "INFO", "msg": "started", "code": 200}
+{"level": "ERROR", "msg": "エラーが発生しました", "code": 503}`
+
+	grep, err := NewGrep("rt", false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := captureStdout(t, func() {
+		ProcessLog(bufio.NewScanner(strings.NewReader(input)), Options{
+			Format: "[{level:<5}] {msg:12}|{code:>4}",
+			Grep:   grep,
+		})
+	})
+	out += captureStdout(t, func() {
+		ProcessLog(bufio.NewScanner(strings.NewReader(input)), Options{
+			Format: "[{level:<5}] {msg:12}|{code:>4}",
+		})
+	})
+
+	want := []string{
+		"[INFO ] started     | 200",
+		"[INFO ] started     | 200",
+		"[ERROR] エラーが発… | 503",
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d:\n%s", len(lines), len(want), out)
+	}
+	for i, line := range lines {
+		if !strings.Contains(line, "\x1b[") {
+			t.Errorf("line %d is not colorized: %q", i, line)
+		}
+		if got := stripANSI(line); got != want[i] {
+			t.Errorf("line %d = %q, want %q", i, got, want[i])
+		}
+	}
+}
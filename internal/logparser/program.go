@@ -0,0 +1,206 @@
+package logparser
+
+import (
+	"slices"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// opcode is the operation of one instruction of a compiled format
+type opcode uint8
+
+const (
+	// opLiteral writes text
+	opLiteral opcode = iota
+	// opField looks up a placeholder, applies its modifiers and spec and writes it
+	opField
+	// opRest writes the fields no other placeholder shows
+	opRest
+	// opSection starts a section, which is kept only when every field in it
+	// is present; {?field: ...} sections jump past their end when the
+	// field is missing
+	opSection
+	// opWord starts a word, which --hide-missing drops when none of its
+	// fields are present
+	opWord
+	// opEnd closes the innermost section or word
+	opEnd
+)
+
+// instruction is one step of a compiled format
+type instruction struct {
+	op       opcode
+	text     string      // literal text
+	field    placeholder // placeholder of opField and opRest
+	timeVal  bool        // field is formatted as a time
+	requires string      // field named by {?field: ...}
+	end      int         // index of the matching opEnd of opSection and opWord
+}
+
+// compileSegments appends the instructions for parsed segments to code
+func compileSegments(code []instruction, segments []segment) []instruction {
+	for _, seg := range segments {
+		switch seg.kind {
+		case segmentLiteral:
+			code = append(code, instruction{op: opLiteral, text: seg.text})
+		case segmentField:
+			in := instruction{op: opField, field: seg.field}
+			switch {
+			case seg.field.field == restField:
+				in.op = opRest
+			case slices.Contains(FieldAliases["timestamp"], seg.field.field):
+				in.timeVal = true
+			}
+			code = append(code, in)
+		case segmentSection, segmentWord:
+			op := opSection
+			if seg.kind == segmentWord {
+				op = opWord
+			}
+			start := len(code)
+			code = append(code, instruction{op: op, requires: seg.requires})
+			code = compileSegments(code, seg.children)
+			code[start].end = len(code)
+			code = append(code, instruction{op: opEnd})
+		}
+	}
+	return code
+}
+
+// groupFrame saves the state of the enclosing group while a section or
+// word runs
+type groupFrame struct {
+	op               opcode
+	mark             int // output length when the group started
+	present, missing bool
+}
+
+// formatRenderer runs a compiled format over records, reusing its output
+// buffer from one record to the next
+type formatRenderer struct {
+	tmpl  *formatTemplate
+	opts  Options
+	buf   []byte
+	stack []groupFrame
+	// timelines of the fields shown with since and delta, by field name
+	timelines map[string]*timeline
+	now       func() time.Time
+}
+
+func newFormatRenderer(tmpl *formatTemplate, opts Options) *formatRenderer {
+	return &formatRenderer{tmpl: tmpl, opts: opts, timelines: make(map[string]*timeline), now: time.Now}
+}
+
+// render formats a record. A group tracks whether any placeholder in it had
+// a value and whether any was missing; groups that are left out do not
+// count as missing for the text around them.
+func (r *formatRenderer) render(rec *record) string {
+	r.buf = r.buf[:0]
+	r.stack = r.stack[:0]
+	present, missing := false, false
+
+	code := r.tmpl.code
+	for pc := 0; pc < len(code); pc++ {
+		in := &code[pc]
+		switch in.op {
+		case opLiteral:
+			r.buf = append(r.buf, in.text...)
+		case opField, opRest:
+			value, ok := r.value(rec, in)
+			switch {
+			case ok:
+				r.buf = append(r.buf, value...)
+				present = true
+			case r.opts.HideMissing || in.op == opRest:
+				// Nothing left over for {rest} is not worth a marker
+				missing = true
+			default:
+				// Mark unknown field in gray with warning symbol, keeping its column width
+				unknownValue := in.field.missing("❓" + in.field.field)
+				r.buf = append(r.buf, color.New(color.FgHiBlack).Sprint(unknownValue)...)
+				missing = true
+			}
+		case opSection, opWord:
+			if in.requires != "" {
+				if v, ok := rec.lookup(in.requires); !ok || FormatValue(v) == "" {
+					pc = in.end
+					continue
+				}
+			}
+			r.stack = append(r.stack, groupFrame{op: in.op, mark: len(r.buf), present: present, missing: missing})
+			present, missing = false, false
+		case opEnd:
+			frame := r.stack[len(r.stack)-1]
+			r.stack = r.stack[:len(r.stack)-1]
+			groupPresent, groupMissing := present, missing
+			present, missing = frame.present, frame.missing
+			switch {
+			case frame.op == opSection:
+				if groupMissing {
+					r.buf = r.buf[:frame.mark]
+				} else {
+					present = true
+				}
+			case !r.opts.HideMissing:
+				present, missing = present || groupPresent, missing || groupMissing
+			case groupPresent:
+				present = true
+			default:
+				// Drop the word with its leading space when none of its fields are present
+				r.buf = r.buf[:frame.mark]
+			}
+		}
+	}
+	return string(r.buf)
+}
+
+// value looks up and renders a placeholder's value, highlighting the text
+// matched by --grep
+func (r *formatRenderer) value(rec *record, in *instruction) (string, bool) {
+	var value any
+	switch {
+	case in.op == opRest:
+		value = r.rest(rec)
+	default:
+		value, _ = rec.lookup(in.field.field)
+		// Show levels by their canonical names unless asked for as written
+		if !r.opts.RawLevel {
+			if level, ok := rec.displayLevel(in.field.field, r.opts.Levels); ok {
+				value = level
+			}
+		}
+	}
+	switch {
+	case in.field.relative != relativeNone:
+		// Relative times are not text of the record, so --grep leaves
+		// them alone and only gaps are colored
+		var gap bool
+		value, gap = r.relative(rec, in, value)
+		text, ok := in.field.render(value)
+		if ok && gap {
+			text = gapHighlight(text)
+		}
+		return text, ok
+	case in.timeVal || (in.field.field == r.opts.Time.Field && r.opts.Time.Field != ""):
+		// Format time fields with timezone conversion
+		value = r.opts.Time.Display(value)
+	}
+	text, ok := in.field.render(value)
+	// Highlight text matched by --grep
+	if ok && r.opts.Grep != nil {
+		text = r.opts.Grep.highlight(text)
+	}
+	return text, ok
+}
+
+// rest returns the record's fields that no other placeholder shows
+func (r *formatRenderer) rest(rec *record) fieldList {
+	used := make(map[string]bool, len(r.tmpl.used))
+	for _, field := range r.tmpl.used {
+		if key, ok := rec.sourceKey(field); ok {
+			used[key] = true
+		}
+	}
+	return rec.orderedFields(used)
+}
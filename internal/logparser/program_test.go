@@ -0,0 +1,129 @@
+package logparser
+
+import (
+	"bufio"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompileSegments(t *testing.T) {
+	tmpl, err := parseFormat("{level}[[ {user}]]{?error: !} {rest}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ops []opcode
+	for _, in := range tmpl.code {
+		ops = append(ops, in.op)
+	}
+	want := []opcode{
+		opWord, opField, opEnd,
+		opSection, opLiteral, opField, opEnd,
+		opSection, opLiteral, opEnd,
+		opWord, opLiteral, opRest, opEnd,
+	}
+	if !reflect.DeepEqual(ops, want) {
+		t.Fatalf("ops = %v, want %v", ops, want)
+	}
+	for _, i := range []int{0, 3, 7, 10} {
+		if end := tmpl.code[i].end; tmpl.code[end].op != opEnd {
+			t.Errorf("instruction %d ends at %d, which is not opEnd", i, end)
+		}
+	}
+	if tmpl.code[7].requires != "error" || tmpl.code[7].end != 9 {
+		t.Errorf("conditional section = %+v", tmpl.code[7])
+	}
+}
+
+func TestFormatRendererReusesBuffer(t *testing.T) {
+	tmpl, err := parseFormat("{level}[[ user={user}]] {message}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newFormatRenderer(tmpl, Options{HideMissing: true})
+
+	lines := []map[string]any{
+		{"level": "INFO", "user": "alice", "msg": "first"},
+		{"level": "WARN", "msg": "second"},
+		{"level": "ERROR", "user": "bob", "msg": "third"},
+	}
+	want := []string{"INFO user=alice first", "WARN second", "ERROR user=bob third"}
+	for i, data := range lines {
+		if got, _ := r.formatRecord(newRecord(data, 2)); got != want[i] {
+			t.Errorf("formatRecord(%v) = %q, want %q", data, got, want[i])
+		}
+	}
+}
+
+const benchLine = `{"time":"2024-03-20T10:00:00.123Z","level":"INFO","msg":"request completed","service":"api","http":{"method":"GET","path":"/v1/users/42","status":200},"latency_ms":12.5,"user":"alice","trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"}`
+
+// Formats measured by the benchmarks; run them with
+// go test -bench . -benchmem ./internal/logparser, or compare ProcessLog
+// with a baseline release with make bench-baseline
+var benchFormats = []struct {
+	name        string
+	format      string
+	hideMissing bool
+}{
+	{name: "Basic", format: "{time} [{level}] {message}"},
+	{name: "Modifiers", format: "{time} [{level:<5|upper}] {service:10} {http.method} {http.path|trunc:30} {latency_ms:6.1f}ms {message}[[ user={user}]]{?error: err={error}}"},
+	{name: "HideMissing", format: "{time} [{level}] {message} (req={request_id}) {user} {caller|basename}", hideMissing: true},
+	{name: "Rest", format: "{time} [{level}] {message} {rest}"},
+}
+
+// benchmarkInput returns n copies of benchLine
+func benchmarkInput(n int) string {
+	return strings.Repeat(benchLine+"\n", n)
+}
+
+// BenchmarkProcessLog measures the whole pipeline, from decoding to printing
+func BenchmarkProcessLog(b *testing.B) {
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer devNull.Close()
+	input := benchmarkInput(1000)
+
+	for _, bf := range benchFormats {
+		b.Run(bf.name, func(b *testing.B) {
+			opts := Options{Format: bf.format, MaxDepth: 2, HideMissing: bf.hideMissing, Time: TimeSettings{Format: "2006-01-02 15:04:05"}}
+			stdout := os.Stdout
+			os.Stdout = devNull
+			defer func() { os.Stdout = stdout }()
+
+			b.SetBytes(int64(len(input)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				ProcessLog(bufio.NewScanner(strings.NewReader(input)), opts)
+			}
+		})
+	}
+}
+
+// BenchmarkFormatRecord measures running a compiled format over a decoded record
+func BenchmarkFormatRecord(b *testing.B) {
+	var data map[string]any
+	if err := decodeJSON(benchLine, &data); err != nil {
+		b.Fatal(err)
+	}
+	for _, bf := range benchFormats {
+		b.Run(bf.name, func(b *testing.B) {
+			tmpl, err := parseFormat(bf.format)
+			if err != nil {
+				b.Fatal(err)
+			}
+			opts := Options{Format: bf.format, HideMissing: bf.hideMissing, Time: TimeSettings{Format: "2006-01-02 15:04:05"}}
+			r := newFormatRenderer(tmpl, opts)
+			rec := newRecord(data, 2)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				r.formatRecord(rec)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package logparser
+
+import (
+	"strings"
+	"time"
+)
+
+// record is a decoded log line together with the fields flattened out of
+// JSON strings in its message, so lookups see the full entry regardless of
+// which fields the output format displays
+type record struct {
+	data    map[string]any
+	message map[string]string
+	// key of the message field when it holds a JSON document
+	jsonMessageKey string
+	// keys lists the top-level keys in the order they were written, when known
+	keys []string
+	// timeField is the field holding the timestamp when set with --time-field
+	timeField string
+	// parsed timestamp, cached by timestamp
+	time               time.Time
+	timeParsed, timeOK bool
+	// normalized level, cached by level
+	levelValue  Level
+	levelParsed bool
+}
+
+// newRecord wraps decoded JSON data and flattens JSON embedded in the message field
+func newRecord(data map[string]any, maxDepth int) *record {
+	r := &record{data: data}
+	for _, alias := range FieldAliases["message"] {
+		v, ok := data[alias]
+		if !ok || v == nil {
+			continue
+		}
+		// Plain text messages have no fields to flatten
+		if s, ok := v.(string); ok && looksLikeJSONObject(s) {
+			r.message = make(map[string]string)
+			flattenJSONString(s, "message", r.message, maxDepth, 1)
+			if _, plain := r.message["message"]; !plain {
+				r.jsonMessageKey = alias
+			}
+		}
+		break
+	}
+	return r
+}
+
+// lookup resolves a field by name, trying aliases, then flattened message
+// fields, then paths into nested objects and arrays
+func (r *record) lookup(field string) (any, bool) {
+	if r.isTimeAlias(field) {
+		return r.lookup(r.timeField)
+	}
+	if v, ok := lookupAlias(r.data, field); ok {
+		return v, true
+	}
+	if v, ok := r.message[field]; ok {
+		return v, true
+	}
+	return LookupPath(r.data, field)
+}
+
+// sourceKey returns the top-level key a field name or path reads from,
+// resolving aliases
+func (r *record) sourceKey(field string) (string, bool) {
+	if r.isTimeAlias(field) {
+		return r.sourceKey(r.timeField)
+	}
+	aliases, exists := FieldAliases[field]
+	if !exists {
+		aliases = []string{field}
+	}
+	for _, alias := range aliases {
+		if v, ok := r.data[alias]; ok && v != nil {
+			return alias, true
+		}
+	}
+	if !isFieldPath(field) {
+		return "", false
+	}
+	segments, err := parsePath(field)
+	if err != nil || segments[0].isIndex || segments[0].wildcard || segments[0].key == field {
+		return "", false
+	}
+	return r.sourceKey(segments[0].key)
+}
+
+// timestamp parses the record's timestamp once and returns it on later calls
+func (r *record) timestamp(ts TimeSettings) (time.Time, bool) {
+	if !r.timeParsed {
+		r.timeParsed = true
+		if v, ok := r.lookup("timestamp"); ok {
+			r.time, r.timeOK = ts.parse(v)
+		}
+	}
+	return r.time, r.timeOK
+}
+
+// isTimeAlias reports whether a field name refers to the --time-field field
+func (r *record) isTimeAlias(field string) bool {
+	return r.timeField != "" && field != r.timeField && (field == "timestamp" || field == "time")
+}
+
+// lookupStrings resolves a field to the string values filters should test:
+// one value per element for wildcard paths, a single value otherwise
+func (r *record) lookupStrings(field string) ([]string, bool) {
+	v, ok := r.lookup(field)
+	if !ok {
+		return nil, false
+	}
+	if items, isArray := v.([]any); isArray && strings.Contains(field, "[*]") {
+		values := make([]string, len(items))
+		for i, item := range items {
+			values[i] = FormatValue(item)
+		}
+		return values, true
+	}
+	return []string{FormatValue(v)}, true
+}
+
+// lookupString resolves a field and converts its value to a string
+func (r *record) lookupString(field string) (string, bool) {
+	v, ok := r.lookup(field)
+	if !ok {
+		return "", false
+	}
+	return FormatValue(v), true
+}
+
+// level normalizes the record's level once and returns it on later calls
+func (r *record) level(levels *Levels) Level {
+	if !r.levelParsed {
+		r.levelParsed = true
+		if key, ok := r.sourceKey("level"); ok {
+			r.levelValue = levels.normalize(key, r.data[key])
+		}
+	}
+	return r.levelValue
+}
+
+// displayLevel returns how a field that reads the record's level is shown:
+// by the level's canonical name, or by its level_mappings name when it is
+// not a recognizable level. Other fields, and levels with neither, report
+// false and are shown as written. The record's data keeps the level as
+// written, so filters and --grep still see it.
+func (r *record) displayLevel(field string, levels *Levels) (string, bool) {
+	if !isLevelField(field) {
+		return "", false
+	}
+	key, ok := r.sourceKey("level")
+	if !ok {
+		return "", false
+	}
+	if fieldKey, _ := r.sourceKey(field); fieldKey != key {
+		return "", false
+	}
+	if level := r.level(levels); level != LevelUnknown {
+		return level.String(), true
+	}
+	if levels != nil {
+		if mapped, ok := levels.mappings[FormatValue(r.data[key])]; ok {
+			return mapped, true
+		}
+	}
+	return "", false
+}
+
+// lookupAlias returns the first non-null value among the field's aliases
+func lookupAlias(data map[string]any, field string) (any, bool) {
+	aliases, exists := FieldAliases[field]
+	if !exists {
+		aliases = []string{field}
+	}
+	for _, alias := range aliases {
+		if v, ok := data[alias]; ok && v != nil {
+			return v, true
+		}
+	}
+	return nil, false
+}
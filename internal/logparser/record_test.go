@@ -0,0 +1,80 @@
+package logparser
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRecordLookup(t *testing.T) {
+	data := map[string]any{
+		"time":  "2024-03-20T10:00:00Z",
+		"lvl":   "INFO",
+		"msg":   `{"user": {"id": "u-1"}}`,
+		"count": float64(1.5),
+		"id":    json.Number("1234567890123456789"),
+		"tags":  []any{"api", json.Number("2")},
+		"ok":    true,
+		"http": map[string]any{
+			"request": map[string]any{"method": "GET"},
+		},
+		"empty": nil,
+	}
+	rec := newRecord(data, 2)
+
+	tests := []struct {
+		name       string
+		field      string
+		wantValue  string
+		wantExists bool
+	}{
+		{name: "Direct field", field: "time", wantValue: "2024-03-20T10:00:00Z", wantExists: true},
+		{name: "Alias field", field: "level", wantValue: "INFO", wantExists: true},
+		{name: "Float field", field: "count", wantValue: "1.5", wantExists: true},
+		{name: "Bool field", field: "ok", wantValue: "true", wantExists: true},
+		{name: "Large integer field", field: "id", wantValue: "1234567890123456789", wantExists: true},
+		{name: "Array field", field: "tags", wantValue: `["api",2]`, wantExists: true},
+		{name: "Nested path", field: "http.request.method", wantValue: "GET", wantExists: true},
+		{name: "Object field", field: "http.request", wantValue: `{"method":"GET"}`, wantExists: true},
+		{name: "Message JSON field", field: "message.user.id", wantValue: "u-1", wantExists: true},
+		{name: "Null field", field: "empty", wantExists: false},
+		{name: "Missing path", field: "http.response.status", wantExists: false},
+		{name: "Missing field", field: "service", wantExists: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, exists := rec.lookupString(tt.field)
+			if exists != tt.wantExists || got != tt.wantValue {
+				t.Errorf("lookupString(%q) = %q, %v, want %q, %v", tt.field, got, exists, tt.wantValue, tt.wantExists)
+			}
+		})
+	}
+}
+
+func TestRecordDisplayLevel(t *testing.T) {
+	levels, err := NewLevels(map[string]string{"40": "WARN", "7": "AUDIT"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		data map[string]any
+		want string
+	}{
+		{data: map[string]any{"level": float64(40)}, want: "WARN"},
+		{data: map[string]any{"level": "warning"}, want: "WARN"},
+		{data: map[string]any{"severityNumber": float64(9)}, want: "INFO"},
+		{data: map[string]any{"level": float64(7)}, want: "AUDIT"},
+		{data: map[string]any{"level": "chatty"}, want: "chatty"},
+	}
+	for _, tt := range tests {
+		rec := newRecord(tt.data, 2)
+		got, ok := rec.displayLevel("level", levels)
+		if !ok {
+			got, _ = rec.lookupString("level")
+		}
+		if got != tt.want {
+			t.Errorf("level of %v = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package logparser
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// relativeMode selects how {field|since}, {field|delta} and {field|ago}
+// show a timestamp
+type relativeMode uint8
+
+const (
+	relativeNone relativeMode = iota
+	// relativeSince shows the offset from the first printed record
+	relativeSince
+	// relativeDelta shows the gap from the previous printed record
+	relativeDelta
+	// relativeAgo shows the age of the timestamp
+	relativeAgo
+)
+
+// relativeModes maps the relative time modifiers to their modes. They read
+// the timestamp itself, so they come first in a modifier chain.
+var relativeModes = map[string]relativeMode{
+	"since": relativeSince,
+	"delta": relativeDelta,
+	"ago":   relativeAgo,
+}
+
+// Color for gaps above Options.GapThreshold
+var gapHighlight = color.New(color.FgHiMagenta, color.Bold).SprintFunc()
+
+// timeline follows a timestamp field across the printed records
+type timeline struct {
+	first, prev, cur time.Time
+	rec              *record // record cur was read from
+}
+
+// advance moves the timeline to a record's time. Placeholders showing the
+// same field share a timeline, so a record only advances it once.
+func (tl *timeline) advance(rec *record, t time.Time) {
+	if tl.rec == rec {
+		return
+	}
+	tl.rec = rec
+	if tl.first.IsZero() {
+		tl.first, tl.cur = t, t
+	}
+	tl.prev, tl.cur = tl.cur, t
+}
+
+// relative renders a placeholder's timestamp relative to the earlier records
+// or the current time, and reports whether the gap from the previous record
+// exceeds the gap threshold. Values that are not timestamps are returned
+// unchanged.
+func (r *formatRenderer) relative(rec *record, in *instruction, value any) (any, bool) {
+	var t time.Time
+	var ok bool
+	if in.timeVal {
+		t, ok = rec.timestamp(r.opts.Time)
+	} else {
+		t, ok = r.opts.Time.parse(value)
+	}
+	if !ok {
+		return value, false
+	}
+
+	if in.field.relative == relativeAgo {
+		return formatAgo(r.now().Sub(t)), false
+	}
+
+	tl := r.timelines[in.field.field]
+	if tl == nil {
+		tl = &timeline{}
+		r.timelines[in.field.field] = tl
+	}
+	tl.advance(rec, t)
+	gap := r.opts.GapThreshold > 0 && t.Sub(tl.prev) > r.opts.GapThreshold
+	if in.field.relative == relativeSince {
+		return formatOffset(t.Sub(tl.first)), gap
+	}
+	return formatOffset(t.Sub(tl.prev)), gap
+}
+
+// formatOffset renders a signed duration as +MM:SS.mmm, with hours when
+// they are needed: +1:02:03.456
+func formatOffset(d time.Duration) string {
+	sign := "+"
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	ms := d.Milliseconds()
+	hours, minutes, seconds := ms/3600000, ms/60000%60, ms/1000%60
+	if hours > 0 {
+		return fmt.Sprintf("%s%d:%02d:%02d.%03d", sign, hours, minutes, seconds, ms%1000)
+	}
+	return fmt.Sprintf("%s%02d:%02d.%03d", sign, minutes, seconds, ms%1000)
+}
+
+// formatAgo renders an age in its largest whole unit, e.g. 3m ago or 2d ago.
+// Timestamps in the future read "in 5s".
+func formatAgo(d time.Duration) string {
+	future := d < 0
+	if future {
+		d = -d
+	}
+	var text string
+	switch {
+	case d < time.Minute:
+		text = fmt.Sprintf("%ds", int(d/time.Second))
+	case d < time.Hour:
+		text = fmt.Sprintf("%dm", int(d/time.Minute))
+	case d < 24*time.Hour:
+		text = fmt.Sprintf("%dh", int(d/time.Hour))
+	default:
+		text = fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+	}
+	if future {
+		return "in " + text
+	}
+	return text + " ago"
+}
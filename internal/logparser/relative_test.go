@@ -0,0 +1,188 @@
+package logparser
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/techarm/jclog/internal/formatter"
+)
+
+func TestFormatOffset(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{d: 0, want: "+00:00.000"},
+		{d: 1234 * time.Millisecond, want: "+00:01.234"},
+		{d: 2*time.Minute + 3*time.Second, want: "+02:03.000"},
+		{d: time.Hour + 2*time.Minute + 3456*time.Millisecond, want: "+1:02:03.456"},
+		{d: -500 * time.Millisecond, want: "-00:00.500"},
+		{d: 999 * time.Microsecond, want: "+00:00.000"},
+	}
+
+	for _, tt := range tests {
+		if got := formatOffset(tt.d); got != tt.want {
+			t.Errorf("formatOffset(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestFormatAgo(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{d: 0, want: "0s ago"},
+		{d: 42 * time.Second, want: "42s ago"},
+		{d: 3*time.Minute + 59*time.Second, want: "3m ago"},
+		{d: 5 * time.Hour, want: "5h ago"},
+		{d: 50 * time.Hour, want: "2d ago"},
+		{d: -5 * time.Second, want: "in 5s"},
+	}
+
+	for _, tt := range tests {
+		if got := formatAgo(tt.d); got != tt.want {
+			t.Errorf("formatAgo(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestRelativeTimestamps(t *testing.T) {
+	oldNoColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = oldNoColor }()
+	// Escape sequence that starts a highlighted gap
+	gapStart, _, _ := strings.Cut(gapHighlight("x"), "x")
+
+	input := `{"time": "2024-03-20T10:00:00Z", "level": "INFO", "msg": "start"}
+{"time": "2024-03-20T10:00:01.234Z", "level": "DEBUG", "msg": "skipped"}
+{"time": "2024-03-20T10:00:01.500Z", "level": "INFO", "msg": "query"}
+{"level": "INFO", "msg": "no time"}
+{"time": 1710928805.5, "level": "INFO", "msg": "slow"}
+{"time": "garbage", "level": "INFO", "msg": "bad time"}`
+
+	tests := []struct {
+		name         string
+		format       string
+		gapThreshold time.Duration
+		grep         string
+		want         []string
+		highlighted  []string
+	}{
+		{
+			name:   "Since first printed record",
+			format: "{timestamp|since} {message}",
+			want:   []string{"+00:00.000 start", "+00:01.500 query", "❓timestamp no time", "+00:05.500 slow", "garbage bad time"},
+		},
+		{
+			name:   "Delta from previous printed record",
+			format: "{timestamp|delta} {message}",
+			want:   []string{"+00:00.000 start", "+00:01.500 query", "❓timestamp no time", "+00:04.000 slow", "garbage bad time"},
+		},
+		{
+			name:   "Since and delta share a timeline",
+			format: "{time|since|pad:11}{time|delta} {message}",
+			want:   []string{"+00:00.000 +00:00.000 start", "+00:01.500 +00:01.500 query", "❓time     ❓time no time", "+00:05.500 +00:04.000 slow", "garbage    garbage bad time"},
+		},
+		{
+			name:         "Gaps highlighted",
+			format:       "{timestamp|delta} {message}",
+			gapThreshold: 2 * time.Second,
+			want:         []string{"+00:00.000 start", "+00:01.500 query", "❓timestamp no time", "+00:04.000 slow", "garbage bad time"},
+			highlighted:  []string{"+00:04.000"},
+		},
+		{
+			name:         "Grep leaves relative times alone",
+			format:       "{timestamp|delta} {message}",
+			gapThreshold: 2 * time.Second,
+			grep:         "0|slow",
+			want:         []string{"+00:00.000 start", "+00:01.500 query", "+00:04.000 slow"},
+			highlighted:  []string{"+00:04.000"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{
+				Format:       tt.format,
+				Excludes:     []Filter{mustParseFilter(t, "level=DEBUG")},
+				GapThreshold: tt.gapThreshold,
+			}
+			if tt.grep != "" {
+				grep, err := NewGrep(tt.grep, false, false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				opts.Grep = grep
+			}
+			out := captureStdout(t, func() {
+				ProcessLog(bufio.NewScanner(strings.NewReader(input)), opts)
+			})
+			lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+			if len(lines) != len(tt.want) {
+				t.Fatalf("got %d lines, want %d: %q", len(lines), len(tt.want), lines)
+			}
+			for i, line := range lines {
+				if got := stripANSI(line); got != tt.want[i] {
+					t.Errorf("line %d = %q, want %q", i, got, tt.want[i])
+				}
+			}
+			if got := strings.Count(out, gapStart); got != len(tt.highlighted) {
+				t.Errorf("got %d highlighted gaps, want %d", got, len(tt.highlighted))
+			}
+			for _, text := range tt.highlighted {
+				if !strings.Contains(out, gapHighlight(text)) {
+					t.Errorf("gap %q not highlighted in %q", text, out)
+				}
+			}
+		})
+	}
+}
+
+func TestGapHighlightKeepsLevelColor(t *testing.T) {
+	oldNoColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = oldNoColor }()
+
+	input := `{"time": "2024-03-20T10:00:00Z", "level": "ERROR", "msg": "start"}
+{"time": "2024-03-20T10:00:05Z", "level": "ERROR", "msg": "slow"}`
+	out := captureStdout(t, func() {
+		ProcessLog(bufio.NewScanner(strings.NewReader(input)), Options{
+			Format:       "{timestamp|delta} [{level}] {message}",
+			GapThreshold: time.Second,
+		})
+	})
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2: %q", len(lines), lines)
+	}
+	if want := formatter.ColorizeByLevel(" [ERROR] slow", "ERROR"); !strings.HasSuffix(lines[1], want) {
+		t.Errorf("text after the gap lost the level color: %q", lines[1])
+	}
+}
+
+func TestRelativeAgo(t *testing.T) {
+	tmpl, err := parseFormat("{timestamp|ago} {message}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newFormatRenderer(tmpl, Options{})
+	r.now = func() time.Time { return time.Date(2024, 3, 20, 10, 3, 30, 0, time.UTC) }
+
+	rec := newRecord(map[string]any{"ts": "2024-03-20T10:00:00Z", "msg": "started"}, 1)
+	if got, want := r.render(rec), "3m ago started"; got != want {
+		t.Errorf("render() = %q, want %q", got, want)
+	}
+}
+
+func TestRelativeModifierOrder(t *testing.T) {
+	if _, err := parsePlaceholder("timestamp|since|pad:12"); err != nil {
+		t.Errorf("parsePlaceholder() error = %v", err)
+	}
+	if _, err := parsePlaceholder("timestamp|upper|delta"); err == nil {
+		t.Error("parsePlaceholder() accepted delta after another modifier")
+	}
+}
@@ -0,0 +1,297 @@
+package logparser
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Layouts tried when parsing timestamps, after a profile's own layouts.
+// Timestamps without an offset are read in the input timezone.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05.000",
+	"2006-01-02T15:04:05",
+}
+
+// Layouts used when no profile layouts are configured
+var defaultTimeLayouts = NewTimeLayouts(nil)
+
+// Additional layouts accepted for --since/--until, interpreted in local time
+var timeBoundLayouts = []string{
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// Time-of-day layouts accepted for --since/--until, applied to the current day
+var timeOfDayLayouts = []string{
+	"15:04:05",
+	"15:04",
+}
+
+// TimeLayouts is an ordered list of input layouts. Logs usually write every
+// timestamp the same way, so the layout that parsed the previous value is
+// tried first.
+type TimeLayouts struct {
+	layouts []string
+	last    atomic.Int32
+}
+
+// NewTimeLayouts creates a layout list trying the given layouts before the
+// built-in ones
+func NewTimeLayouts(layouts []string) *TimeLayouts {
+	return &TimeLayouts{layouts: slices.Concat(layouts, timeLayouts)}
+}
+
+// ValidateTimeLayout checks that a Go time layout contains date or time
+// elements and can parse what it formats
+func ValidateTimeLayout(layout string) error {
+	// A layout without elements formats every time as itself; the sample
+	// differs from the reference time so compact layouts are not mistaken
+	// for that
+	sample := time.Date(2024, 11, 28, 21, 30, 45, 0, time.UTC)
+	formatted := sample.Format(layout)
+	if formatted == layout {
+		return fmt.Errorf("invalid time layout %q: use Go's reference time, as in 02/Jan/2006:15:04:05 -0700", layout)
+	}
+	if _, err := time.Parse(layout, formatted); err != nil {
+		return fmt.Errorf("invalid time layout %q: %v", layout, err)
+	}
+	return nil
+}
+
+// parse parses a timestamp string; timestamps without an offset are read in loc
+func (l *TimeLayouts) parse(value string, loc *time.Location) (time.Time, bool) {
+	last := int(l.last.Load())
+	if t, err := time.ParseInLocation(l.layouts[last], value, loc); err == nil {
+		return t, true
+	}
+	for i, layout := range l.layouts {
+		if i == last {
+			continue
+		}
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			l.last.Store(int32(i))
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// TimeSettings controls how timestamps are read and displayed
+type TimeSettings struct {
+	// Format is the layout timestamps are displayed with; empty shows them as written
+	Format string
+	// EpochUnit is the unit of numeric timestamps; zero detects it by magnitude
+	EpochUnit time.Duration
+	// Location is the timezone timestamps are displayed in; nil means local time
+	Location *time.Location
+	// InputLocation is the timezone of timestamps written without an
+	// offset; nil means UTC
+	InputLocation *time.Location
+	// Layouts parses timestamp strings; nil uses the built-in layouts
+	Layouts *TimeLayouts
+	// Field is the field holding the timestamp, when it is not one of the
+	// timestamp aliases; {time} and {timestamp} then show it
+	Field string
+}
+
+// LoadTimezone loads a timezone by IANA name, such as Asia/Tokyo, or UTC or Local
+func LoadTimezone(name string) (*time.Location, error) {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: expected an IANA name such as Europe/Berlin, UTC or Local", name)
+	}
+	return loc, nil
+}
+
+// location returns the display timezone
+func (s TimeSettings) location() *time.Location {
+	if s.Location == nil {
+		return time.Local
+	}
+	return s.Location
+}
+
+// Units accepted for a profile's epoch_precision
+var epochUnits = map[string]time.Duration{
+	"s":  time.Second,
+	"ms": time.Millisecond,
+	"us": time.Microsecond,
+	"ns": time.Nanosecond,
+}
+
+// ParseEpochUnit parses the unit of numeric timestamps: s, ms, us or ns,
+// or auto (or empty) to detect it by magnitude
+func ParseEpochUnit(value string) (time.Duration, error) {
+	if value == "" || value == "auto" {
+		return 0, nil
+	}
+	unit, ok := epochUnits[value]
+	if !ok {
+		return 0, fmt.Errorf("invalid epoch precision %q: expected auto, s, ms, us or ns", value)
+	}
+	return unit, nil
+}
+
+// parse converts a decoded JSON value into a time. Strings are parsed with
+// the supported layouts; numbers and numeric strings are epoch values.
+func (s TimeSettings) parse(v any) (time.Time, bool) {
+	switch v := v.(type) {
+	case string:
+		loc := s.InputLocation
+		if loc == nil {
+			loc = time.UTC
+		}
+		layouts := s.Layouts
+		if layouts == nil {
+			layouts = defaultTimeLayouts
+		}
+		if t, ok := layouts.parse(v, loc); ok {
+			return t, true
+		}
+		return parseEpochText(v, s.EpochUnit)
+	case float64:
+		return parseEpoch(v, s.EpochUnit)
+	default:
+		if _, ok := numberValue(v); ok {
+			return parseEpochText(FormatValue(v), s.EpochUnit)
+		}
+	}
+	return time.Time{}, false
+}
+
+// Display formats a timestamp value with the display layout in the display
+// timezone. Values that are not timestamps are returned unchanged.
+func (s TimeSettings) Display(v any) any {
+	if s.Format == "" {
+		return v
+	}
+	t, ok := s.parse(v)
+	if !ok {
+		return v
+	}
+	return t.In(s.location()).Format(s.Format)
+}
+
+// parseEpochText parses an epoch number, exactly when it is an integer
+func parseEpochText(text string, unit time.Duration) (time.Time, bool) {
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		if n <= 0 {
+			return time.Time{}, false
+		}
+		if unit == 0 {
+			unit = epochUnit(float64(n))
+		}
+		return epochTime(n, unit), true
+	}
+	n, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return parseEpoch(n, unit)
+}
+
+// parseEpoch converts an epoch value to a time, keeping fractions down to
+// microseconds
+func parseEpoch(n float64, unit time.Duration) (time.Time, bool) {
+	if n <= 0 || math.IsInf(n, 0) || math.IsNaN(n) || n >= math.MaxInt64 {
+		return time.Time{}, false
+	}
+	if unit == 0 {
+		unit = epochUnit(n)
+	}
+	whole, frac := math.Modf(n)
+	micros := math.Round(frac * float64(unit) / float64(time.Microsecond))
+	return epochTime(int64(whole), unit).Add(time.Duration(micros) * time.Microsecond), true
+}
+
+// epochUnit detects the unit of an epoch value by its magnitude
+func epochUnit(n float64) time.Duration {
+	switch {
+	case n < 1e11: // seconds (until year 5138)
+		return time.Second
+	case n < 1e14:
+		return time.Millisecond
+	case n < 1e17:
+		return time.Microsecond
+	default:
+		return time.Nanosecond
+	}
+}
+
+// epochTime converts a whole number of units since the epoch to a time
+func epochTime(n int64, unit time.Duration) time.Time {
+	switch unit {
+	case time.Second:
+		return time.Unix(n, 0)
+	case time.Millisecond:
+		return time.UnixMilli(n)
+	case time.Microsecond:
+		return time.UnixMicro(n)
+	default:
+		return time.Unix(0, n)
+	}
+}
+
+// ParseTimeBound parses a --since/--until value: an absolute timestamp,
+// a time of day, a relative duration before now (15m, 2h, 7d), an epoch
+// value, or one of the keywords now, today and yesterday
+func ParseTimeBound(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch strings.ToLower(value) {
+	case "now":
+		return now, nil
+	case "today":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	}
+
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	for _, layout := range slices.Concat(timeLayouts, timeBoundLayouts) {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range timeOfDayLayouts {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			return today.Add(time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second), nil
+		}
+	}
+	if t, ok := parseEpochText(value, 0); ok {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: expected a timestamp, a duration like 15m or 2h, or now/today/yesterday", value)
+}
+
+// matchTimeWindow checks whether the record's timestamp lies within [since, until].
+// Records without a parseable timestamp match only if keepMissing is set.
+func matchTimeWindow(rec *record, since, until time.Time, keepMissing bool, ts TimeSettings) bool {
+	t, ok := rec.timestamp(ts)
+	if !ok {
+		return keepMissing
+	}
+	if !since.IsZero() && t.Before(since) {
+		return false
+	}
+	if !until.IsZero() && t.After(until) {
+		return false
+	}
+	return true
+}
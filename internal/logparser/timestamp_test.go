@@ -0,0 +1,249 @@
+package logparser
+
+import (
+	"bufio"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeSettingsParse(t *testing.T) {
+	want := time.Date(2024, 3, 20, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		value any
+		want  time.Time
+		ok    bool
+	}{
+		{name: "RFC3339", value: "2024-03-20T10:00:00Z", want: want, ok: true},
+		{name: "Offset", value: "2024-03-20T19:00:00+09:00", want: want, ok: true},
+		{name: "Datetime", value: "2024-03-20 10:00:00", want: want, ok: true},
+		{name: "Epoch seconds", value: float64(1710928800), want: want, ok: true},
+		{name: "Epoch float seconds", value: 1710928800.25, want: want.Add(250 * time.Millisecond), ok: true},
+		{name: "Epoch milliseconds", value: float64(1710928800123), want: want.Add(123 * time.Millisecond), ok: true},
+		{name: "Epoch microseconds", value: float64(1710928800123456), want: want.Add(123456 * time.Microsecond), ok: true},
+		{name: "Epoch nanoseconds", value: float64(1710928800123456789), want: want.Add(123456768 * time.Nanosecond), ok: true},
+		{name: "Epoch string", value: "1710928800", want: want, ok: true},
+		{name: "Invalid string", value: "yesterday-ish", ok: false},
+		{name: "Bool", value: true, ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := TimeSettings{}.parse(tt.value)
+			if ok != tt.ok {
+				t.Fatalf("parse(%v) ok = %v, want %v", tt.value, ok, tt.ok)
+			}
+			if ok && !got.Equal(tt.want) {
+				t.Errorf("parse(%v) = %v, want %v", tt.value, got.UTC(), tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeSettingsDisplay(t *testing.T) {
+	oldLocal := time.Local
+	time.Local = time.UTC
+	defer func() { time.Local = oldLocal }()
+
+	tests := []struct {
+		name      string
+		value     any
+		epochUnit string
+		tz        string
+		inputTZ   string
+		want      any
+	}{
+		{name: "String timestamp", value: "2024-03-20T19:00:00+09:00", want: "2024-03-20 10:00:00.000"},
+		{name: "zap float seconds", value: json.Number("1647763200.123"), want: "2022-03-20 08:00:00.123"},
+		{name: "pino milliseconds", value: json.Number("1647763200123"), want: "2022-03-20 08:00:00.123"},
+		{name: "Nanoseconds stay exact", value: json.Number("1647763200123456789"), want: "2022-03-20 08:00:00.123"},
+		{name: "Forced milliseconds", value: json.Number("1647763200"), epochUnit: "ms", want: "1970-01-20 01:42:43.200"},
+		{name: "Forced microseconds", value: "1647763200123456", epochUnit: "us", want: "2022-03-20 08:00:00.123"},
+		{name: "Not a timestamp", value: "soon", want: "soon"},
+		{name: "Missing", value: nil, want: nil},
+		{name: "Display timezone", value: "2024-03-20T10:00:00Z", tz: "Asia/Tokyo", want: "2024-03-20 19:00:00.000"},
+		{name: "Epoch in display timezone", value: json.Number("1647763200"), tz: "Europe/Berlin", want: "2022-03-20 09:00:00.000"},
+		{name: "Naive timestamp read as UTC", value: "2024-03-20 10:00:00", tz: "Asia/Tokyo", want: "2024-03-20 19:00:00.000"},
+		{name: "Naive timestamp in input timezone", value: "2024-03-20 10:00:00", tz: "UTC", inputTZ: "Asia/Tokyo", want: "2024-03-20 01:00:00.000"},
+		{name: "Offset wins over input timezone", value: "2024-03-20T10:00:00+01:00", inputTZ: "Asia/Tokyo", want: "2024-03-20 09:00:00.000"},
+		{name: "ISO without offset", value: "2024-03-20T10:00:00.5", inputTZ: "Europe/Berlin", want: "2024-03-20 09:00:00.500"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unit, err := ParseEpochUnit(tt.epochUnit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ts := TimeSettings{Format: "2006-01-02 15:04:05.000", EpochUnit: unit}
+			if tt.tz != "" {
+				if ts.Location, err = LoadTimezone(tt.tz); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.inputTZ != "" {
+				if ts.InputLocation, err = LoadTimezone(tt.inputTZ); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := ts.Display(tt.value); got != tt.want {
+				t.Errorf("Display(%v) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseEpochUnit("minutes"); err == nil {
+		t.Error("ParseEpochUnit() accepted an unknown unit")
+	}
+	if _, err := LoadTimezone("Mars/Olympus"); err == nil {
+		t.Error("LoadTimezone() accepted an unknown timezone")
+	}
+}
+
+func TestTimestampAliasesDisplay(t *testing.T) {
+	tmpl, err := parseFormat("{timestamp} {ts}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newFormatRenderer(tmpl, Options{Time: TimeSettings{Format: "15:04:05", Location: time.UTC}})
+	rec := newRecord(map[string]any{"ts": json.Number("1710928800")}, 1)
+	if got, want := stripANSI(r.render(rec)), "10:00:00 10:00:00"; got != want {
+		t.Errorf("render() = %q, want %q", got, want)
+	}
+}
+
+func TestTimeLayouts(t *testing.T) {
+	want := time.Date(2024, 3, 20, 10, 0, 0, 0, time.UTC)
+	layouts := NewTimeLayouts([]string{"02/Jan/2006:15:04:05 -0700", "20060102T150405"})
+
+	tests := []struct {
+		name     string
+		value    string
+		want     time.Time
+		ok       bool
+		wantLast int32
+	}{
+		{name: "First custom layout", value: "20/Mar/2024:19:00:00 +0900", want: want, ok: true, wantLast: 0},
+		{name: "Second custom layout", value: "20240320T100000", want: want, ok: true, wantLast: 1},
+		{name: "Cached layout", value: "20240320T100000", want: want, ok: true, wantLast: 1},
+		{name: "Built-in layout", value: "2024-03-20T10:00:00Z", want: want, ok: true, wantLast: 2},
+		{name: "No match keeps cache", value: "March 20th", ok: false, wantLast: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := TimeSettings{Layouts: layouts}.parse(tt.value)
+			if ok != tt.ok {
+				t.Fatalf("parse(%q) ok = %v, want %v", tt.value, ok, tt.ok)
+			}
+			if ok && !got.Equal(tt.want) {
+				t.Errorf("parse(%q) = %v, want %v", tt.value, got.UTC(), tt.want)
+			}
+			if last := layouts.last.Load(); last != tt.wantLast {
+				t.Errorf("last layout = %d, want %d", last, tt.wantLast)
+			}
+		})
+	}
+
+	for layout, wantErr := range map[string]bool{
+		"02/Jan/2006:15:04:05 -0700": false,
+		"20060102T150405":            false,
+		"yyyy-MM-dd HH:mm:ss":        true,
+		"":                           true,
+	} {
+		if err := ValidateTimeLayout(layout); (err != nil) != wantErr {
+			t.Errorf("ValidateTimeLayout(%q) error = %v, wantErr %v", layout, err, wantErr)
+		}
+	}
+}
+
+func TestTimeField(t *testing.T) {
+	input := `{"created_at": "20/Mar/2024:19:00:00 +0900", "time": "ignored", "msg": "first"}
+{"created_at": "20/Mar/2024:12:00:00 +0900", "time": "ignored", "msg": "second"}`
+	ts := TimeSettings{
+		Format:   "15:04:05",
+		Location: time.UTC,
+		Layouts:  NewTimeLayouts([]string{"02/Jan/2006:15:04:05 -0700"}),
+		Field:    "created_at",
+	}
+
+	out := captureStdout(t, func() {
+		ProcessLog(bufio.NewScanner(strings.NewReader(input)), Options{
+			Format: "{timestamp} {message} {rest}",
+			Since:  time.Date(2024, 3, 20, 9, 0, 0, 0, time.UTC),
+			Time:   ts,
+		})
+	})
+	if got, want := stripANSI(out), "10:00:00 first time=ignored\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestParseTimeBound(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	now := time.Date(2024, 3, 20, 19, 30, 0, 0, loc)
+	today := time.Date(2024, 3, 20, 0, 0, 0, 0, loc)
+
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "now", want: now},
+		{value: "today", want: today},
+		{value: "yesterday", want: today.AddDate(0, 0, -1)},
+		{value: "15m", want: now.Add(-15 * time.Minute)},
+		{value: "2h", want: now.Add(-2 * time.Hour)},
+		{value: "7d", want: now.AddDate(0, 0, -7)},
+		{value: "2024-03-20T10:02:00Z", want: time.Date(2024, 3, 20, 10, 2, 0, 0, time.UTC)},
+		{value: "2024-03-20 10:02:00", want: time.Date(2024, 3, 20, 10, 2, 0, 0, loc)},
+		{value: "2024-03-19", want: time.Date(2024, 3, 19, 0, 0, 0, 0, loc)},
+		{value: "10:05", want: time.Date(2024, 3, 20, 10, 5, 0, 0, loc)},
+		{value: "1710928800", want: time.Date(2024, 3, 20, 10, 0, 0, 0, time.UTC)},
+		{value: "last week", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseTimeBound(tt.value, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTimeBound(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if !tt.wantErr && !got.Equal(tt.want) {
+				t.Errorf("ParseTimeBound(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchTimeWindow(t *testing.T) {
+	since := time.Date(2024, 3, 20, 10, 2, 0, 0, time.UTC)
+	until := time.Date(2024, 3, 20, 10, 5, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		data        map[string]any
+		keepMissing bool
+		want        bool
+	}{
+		{name: "Inside window", data: map[string]any{"timestamp": "2024-03-20T10:03:00Z"}, want: true},
+		{name: "Before window", data: map[string]any{"time": "2024-03-20T10:01:59Z"}, want: false},
+		{name: "After window", data: map[string]any{"time": "2024-03-20T10:05:01Z"}, want: false},
+		{name: "Inclusive bounds", data: map[string]any{"time": "2024-03-20T10:05:00Z"}, want: true},
+		{name: "Epoch alias", data: map[string]any{"ts": float64(1710928980)}, want: true},
+		{name: "Missing time hidden", data: map[string]any{"msg": "no time"}, want: false},
+		{name: "Missing time shown", data: map[string]any{"msg": "no time"}, keepMissing: true, want: true},
+		{name: "Unparseable time shown", data: map[string]any{"time": "soon"}, keepMissing: true, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchTimeWindow(newRecord(tt.data, 2), since, until, tt.keepMissing, TimeSettings{}); got != tt.want {
+				t.Errorf("matchTimeWindow() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package logparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// decodeJSON decodes a JSON document, keeping numbers as json.Number so
+// large integer IDs are not rounded through float64
+func decodeJSON(data string, v any) error {
+	return decodeJSONFrom(strings.NewReader(data), v)
+}
+
+// decodeJSONFrom decodes a JSON document read from r like decodeJSON, so
+// callers can decode bytes without copying them to a string
+func decodeJSONFrom(r io.Reader, v any) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("invalid character after top-level value")
+	}
+	return nil
+}
+
+// FormatValue renders a decoded JSON value for display. Numbers keep their
+// precision and never use exponents, booleans print as true/false and
+// objects and arrays render as compact JSON.
+func FormatValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return formatNumber(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case *big.Int:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case fieldList:
+		return v.String()
+	case nil:
+		return ""
+	default:
+		return encodeJSON(v)
+	}
+}
+
+// encodeJSON renders a value as compact JSON without HTML escaping
+func encodeJSON(v any) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// formatNumber prints integer literals exactly as written and expands
+// fractional or exponent forms without an exponent
+func formatNumber(n json.Number) string {
+	s := n.String()
+	if !strings.ContainsAny(s, ".eE") {
+		return s
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return s
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// numberValue converts a decoded JSON number to float64
+func numberValue(v any) (float64, bool) {
+	switch v := v.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(v).Float64()
+		return f, true
+	}
+	return 0, false
+}
+
+// ValueType names the JSON type of a decoded value
+func ValueType(v any) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case json.Number, float64, int, int64, *big.Int:
+		return "number"
+	case bool:
+		return "bool"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,90 @@
+package logparser
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		field   string
+		want    string
+		wantErr bool
+	}{
+		{name: "Large integer keeps precision", input: `{"id": 1234567890123456789}`, field: "id", want: "1234567890123456789"},
+		{name: "Float keeps precision", input: `{"ratio": 0.95}`, field: "ratio", want: "0.95"},
+		{name: "Exponent expanded", input: `{"bytes": 1.5e6}`, field: "bytes", want: "1500000"},
+		{name: "Trailing data", input: `{"a": 1} {"b": 2}`, wantErr: true},
+		{name: "Not an object", input: `[1, 2]`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data map[string]any
+			err := decodeJSON(tt.input, &data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr {
+				if got := FormatValue(data[tt.field]); got != tt.want {
+					t.Errorf("FormatValue(%v) = %q, want %q", data[tt.field], got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	bigID, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{name: "String", value: "text", want: "text"},
+		{name: "Integer number", value: json.Number("42"), want: "42"},
+		{name: "Fractional number", value: json.Number("1.50"), want: "1.5"},
+		{name: "Negative exponent", value: json.Number("2.5e-3"), want: "0.0025"},
+		{name: "Float", value: 0.95, want: "0.95"},
+		{name: "Large float", value: 1e21, want: "1000000000000000000000"},
+		{name: "Int", value: 7, want: "7"},
+		{name: "Big integer", value: bigID, want: "123456789012345678901234567890"},
+		{name: "Bool", value: true, want: "true"},
+		{name: "Null", value: nil, want: ""},
+		{name: "Object", value: map[string]any{"b": json.Number("1"), "a": "x&y"}, want: `{"a":"x&y","b":1}`},
+		{name: "Array", value: []any{json.Number("1"), "two", false}, want: `[1,"two",false]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatValue(tt.value); got != tt.want {
+				t.Errorf("FormatValue(%v) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValueType(t *testing.T) {
+	tests := []struct {
+		value any
+		want  string
+	}{
+		{value: "text", want: "string"},
+		{value: json.Number("1"), want: "number"},
+		{value: 1.5, want: "number"},
+		{value: true, want: "bool"},
+		{value: map[string]any{}, want: "object"},
+		{value: []any{}, want: "array"},
+		{value: nil, want: "null"},
+	}
+
+	for _, tt := range tests {
+		if got := ValueType(tt.value); got != tt.want {
+			t.Errorf("ValueType(%v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
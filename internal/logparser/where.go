@@ -0,0 +1,511 @@
+package logparser
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Where is a compiled boolean filter expression such as
+// (level == "ERROR" || http_code >= 500) && !(uri startsWith "/health")
+type Where struct {
+	expr string
+	root whereNode
+}
+
+// ParseWhere compiles a where expression
+func ParseWhere(expr string) (*Where, error) {
+	tokens, err := tokenizeWhere(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid where expression %q: %v", expr, err)
+	}
+	p := &whereParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && p.peek().kind != tokenEOF {
+		err = fmt.Errorf("unexpected %s at position %d", p.peek(), p.peek().pos)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid where expression %q: %v", expr, err)
+	}
+	return &Where{expr: expr, root: root}, nil
+}
+
+// String returns the source expression
+func (w *Where) String() string {
+	return w.expr
+}
+
+// match evaluates the expression against a record, reading its level with
+// the level tables and timestamps with ts
+func (w *Where) match(rec *record, levels *Levels, ts TimeSettings) bool {
+	return w.root.eval(rec, whereEnv{levels: levels, time: ts})
+}
+
+// whereEnv holds the settings an expression reads values with
+type whereEnv struct {
+	levels *Levels
+	time   TimeSettings
+}
+
+// Expression tree
+
+type whereNode interface {
+	eval(rec *record, env whereEnv) bool
+}
+
+type operand interface {
+	value(rec *record, levels *Levels) (string, bool)
+}
+
+type andNode struct{ left, right whereNode }
+
+func (n andNode) eval(rec *record, env whereEnv) bool {
+	return n.left.eval(rec, env) && n.right.eval(rec, env)
+}
+
+type orNode struct{ left, right whereNode }
+
+func (n orNode) eval(rec *record, env whereEnv) bool {
+	return n.left.eval(rec, env) || n.right.eval(rec, env)
+}
+
+type notNode struct{ expr whereNode }
+
+func (n notNode) eval(rec *record, env whereEnv) bool { return !n.expr.eval(rec, env) }
+
+type existsNode struct{ field string }
+
+func (n existsNode) eval(rec *record, _ whereEnv) bool {
+	_, ok := rec.lookup(n.field)
+	return ok
+}
+
+// truthyNode evaluates a bare operand: missing, null, false, 0 and "" are false
+type truthyNode struct{ operand operand }
+
+func (n truthyNode) eval(rec *record, env whereEnv) bool {
+	v, ok := n.operand.value(rec, env.levels)
+	if !ok {
+		return false
+	}
+	switch v {
+	case "", "false", "0":
+		return false
+	}
+	return true
+}
+
+type compareNode struct {
+	op          string
+	left, right operand
+	pattern     *regexp.Regexp
+}
+
+func (n compareNode) eval(rec *record, env whereEnv) bool {
+	l, lok := n.left.value(rec, env.levels)
+	r, rok := n.right.value(rec, env.levels)
+	compare := func(l, r string) int { return compareValues(l, r, env.time) }
+	if isLevelOperand(n.left) || isLevelOperand(n.right) {
+		compare = func(l, r string) int { return compareLevels(l, r, env.levels) }
+	}
+
+	switch n.op {
+	case "==":
+		if !lok || !rok {
+			return lok == rok
+		}
+		return compare(l, r) == 0
+	case "!=":
+		if !lok || !rok {
+			return lok != rok
+		}
+		return compare(l, r) != 0
+	}
+
+	if !lok || !rok {
+		return false
+	}
+	switch n.op {
+	case ">":
+		return compare(l, r) > 0
+	case ">=":
+		return compare(l, r) >= 0
+	case "<":
+		return compare(l, r) < 0
+	case "<=":
+		return compare(l, r) <= 0
+	case "contains":
+		return strings.Contains(l, r)
+	case "startsWith":
+		return strings.HasPrefix(l, r)
+	case "endsWith":
+		return strings.HasSuffix(l, r)
+	case "matches":
+		return n.pattern.MatchString(l)
+	}
+	return false
+}
+
+// compareLevels orders two level values by severity when both are
+// recognizable levels, so level == "warning" and level >= "WARN" hold for
+// every spelling of the level
+func compareLevels(l, r string, levels *Levels) int {
+	ll, rl := levels.normalize("level", l), levels.normalize("level", r)
+	if ll == LevelUnknown || rl == LevelUnknown {
+		return compareValues(l, r, TimeSettings{})
+	}
+	return cmp.Compare(ll, rl)
+}
+
+type fieldOperand struct{ name string }
+
+// value returns the field's value; level fields read as the normalized
+// level, as {level} shows it, when the record's level is recognizable
+func (o fieldOperand) value(rec *record, levels *Levels) (string, bool) {
+	if isLevelField(o.name) {
+		if level := rec.level(levels); level != LevelUnknown {
+			return level.String(), true
+		}
+	}
+	return rec.lookupString(o.name)
+}
+
+// isLevelOperand reports whether an operand reads the level
+func isLevelOperand(o operand) bool {
+	f, ok := o.(fieldOperand)
+	return ok && isLevelField(f.name)
+}
+
+// literalOperand is a constant; null literals report as missing
+type literalOperand struct {
+	text string
+	null bool
+}
+
+func (o literalOperand) value(*record, *Levels) (string, bool) { return o.text, !o.null }
+
+// Tokenizer
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+)
+
+type whereToken struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t whereToken) String() string {
+	if t.kind == tokenEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(t.text)
+}
+
+// Operators recognized by the tokenizer, longest first
+var whereOperators = []string{"&&", "||", "==", "!=", ">=", "<=", "=~", "(", ")", ",", "!", ">", "<", "="}
+
+func tokenizeWhere(expr string) ([]whereToken, error) {
+	var tokens []whereToken
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(expr) && expr[end] != byte(c) {
+				if expr[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expr) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			text, err := unquoteWhereString(expr[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at position %d: %v", i, err)
+			}
+			tokens = append(tokens, whereToken{kind: tokenString, text: text, pos: i})
+			i = end + 1
+		case unicode.IsDigit(c) || (c == '-' && i+1 < len(expr) && unicode.IsDigit(rune(expr[i+1]))):
+			end := i + 1
+			for end < len(expr) && (unicode.IsDigit(rune(expr[end])) || strings.ContainsRune(".eE+-", rune(expr[end]))) {
+				end++
+			}
+			if _, err := strconv.ParseFloat(expr[i:end], 64); err != nil {
+				return nil, fmt.Errorf("invalid number %q at position %d", expr[i:end], i)
+			}
+			tokens = append(tokens, whereToken{kind: tokenNumber, text: expr[i:end], pos: i})
+			i = end
+		case isIdentStart(c):
+			end := i + 1
+			for end < len(expr) && isIdentPart(rune(expr[end])) {
+				end++
+			}
+			tokens = append(tokens, whereToken{kind: tokenIdent, text: expr[i:end], pos: i})
+			i = end
+		default:
+			matched := false
+			for _, op := range whereOperators {
+				if strings.HasPrefix(expr[i:], op) {
+					tokens = append(tokens, whereToken{kind: tokenOperator, text: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+			}
+		}
+	}
+	return append(tokens, whereToken{kind: tokenEOF, pos: len(expr)}), nil
+}
+
+// unquoteWhereString decodes a single- or double-quoted string literal
+func unquoteWhereString(s string) (string, error) {
+	if s[0] == '\'' {
+		s = `"` + strings.ReplaceAll(strings.ReplaceAll(s[1:len(s)-1], `\'`, `'`), `"`, `\"`) + `"`
+	}
+	return strconv.Unquote(s)
+}
+
+func isIdentStart(c rune) bool {
+	return unicode.IsLetter(c) || c == '_' || c == '@' || c == '$'
+}
+
+func isIdentPart(c rune) bool {
+	return isIdentStart(c) || unicode.IsDigit(c) || strings.ContainsRune(".-[]*", c)
+}
+
+// Parser
+
+// String operators usable both infix (uri startsWith "/api") and as functions (startsWith(uri, "/api"))
+var whereStringOperators = map[string]bool{
+	"contains":   true,
+	"startsWith": true,
+	"endsWith":   true,
+	"matches":    true,
+}
+
+type whereParser struct {
+	tokens []whereToken
+	pos    int
+}
+
+func (p *whereParser) peek() whereToken {
+	return p.tokens[p.pos]
+}
+
+func (p *whereParser) next() whereToken {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is one of the given operators or keywords
+func (p *whereParser) accept(texts ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokenOperator && t.kind != tokenIdent {
+		return "", false
+	}
+	for _, text := range texts {
+		if t.text == text {
+			p.next()
+			return text, true
+		}
+	}
+	return "", false
+}
+
+func (p *whereParser) expect(text string) error {
+	if _, ok := p.accept(text); !ok {
+		return fmt.Errorf("expected %q but found %s at position %d", text, p.peek(), p.peek().pos)
+	}
+	return nil
+}
+
+func (p *whereParser) parseOr() (whereNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("||", "or"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+}
+
+func (p *whereParser) parseAnd() (whereNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("&&", "and"); !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+}
+
+func (p *whereParser) parseUnary() (whereNode, error) {
+	if _, ok := p.accept("!", "not"); ok {
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{expr}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *whereParser) parsePrimary() (whereNode, error) {
+	if _, ok := p.accept("("); ok {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	}
+
+	// Function calls: exists(field), contains(a, b), ...
+	if t := p.peek(); t.kind == tokenIdent && p.tokens[p.pos+1].text == "(" {
+		return p.parseCall()
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	op := t.text
+	switch {
+	case t.kind == tokenOperator && (op == "==" || op == "=" || op == "!=" || op == ">" || op == ">=" || op == "<" || op == "<=" || op == "=~"):
+	case t.kind == tokenIdent && whereStringOperators[op]:
+	default:
+		return truthyNode{left}, nil
+	}
+	p.next()
+
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return newCompareNode(op, left, right)
+}
+
+func (p *whereParser) parseCall() (whereNode, error) {
+	name := p.next()
+	p.next() // (
+
+	var args []operand
+	for p.peek().text != ")" || p.peek().kind != tokenOperator {
+		if len(args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	p.next() // )
+
+	switch {
+	case name.text == "exists":
+		field, ok := singleFieldArg(args)
+		if !ok {
+			return nil, fmt.Errorf("exists() takes a single field name at position %d", name.pos)
+		}
+		return existsNode{field}, nil
+	case whereStringOperators[name.text]:
+		if len(args) != 2 {
+			return nil, fmt.Errorf("%s() takes 2 arguments at position %d", name.text, name.pos)
+		}
+		return newCompareNode(name.text, args[0], args[1])
+	}
+	return nil, fmt.Errorf("unknown function %q at position %d", name.text, name.pos)
+}
+
+func (p *whereParser) parseOperand() (operand, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString, tokenNumber:
+		return literalOperand{text: t.text}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true", "false":
+			return literalOperand{text: t.text}, nil
+		case "null":
+			return literalOperand{null: true}, nil
+		}
+		return fieldOperand{t.text}, nil
+	}
+	return nil, fmt.Errorf("expected a field or value but found %s at position %d", t, t.pos)
+}
+
+func newCompareNode(op string, left, right operand) (whereNode, error) {
+	switch op {
+	case "=":
+		op = "=="
+	case "=~":
+		op = "matches"
+	}
+	n := compareNode{op: op, left: left, right: right}
+	if op == "matches" {
+		lit, ok := right.(literalOperand)
+		if !ok || lit.null {
+			return nil, fmt.Errorf("matches requires a string pattern")
+		}
+		re, err := regexp.Compile(lit.text)
+		if err != nil {
+			return nil, err
+		}
+		n.pattern = re
+	}
+	return n, nil
+}
+
+func singleFieldArg(args []operand) (string, bool) {
+	if len(args) != 1 {
+		return "", false
+	}
+	switch arg := args[0].(type) {
+	case fieldOperand:
+		return arg.name, true
+	case literalOperand:
+		return arg.text, !arg.null
+	}
+	return "", false
+}
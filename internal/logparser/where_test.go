@@ -0,0 +1,170 @@
+package logparser
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseWhere(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		wantErr bool
+	}{
+		{name: "Comparison", expr: `level == "ERROR"`},
+		{name: "Boolean operators", expr: `(level == "ERROR" || http_code >= 500) && !(uri startsWith "/health")`},
+		{name: "Keyword operators", expr: `level = 'WARN' and not exists(user) or retries > 3`},
+		{name: "Function calls", expr: `contains(msg, "timeout") && matches(uri, "^/api/v[0-9]+")`},
+		{name: "Bare field", expr: `debug`},
+		{name: "Unbalanced parenthesis", expr: `(level == "ERROR"`, wantErr: true},
+		{name: "Unterminated string", expr: `level == "ERROR`, wantErr: true},
+		{name: "Missing operand", expr: `level ==`, wantErr: true},
+		{name: "Trailing tokens", expr: `level == "ERROR" "WARN"`, wantErr: true},
+		{name: "Unknown function", expr: `lower(level) == "error"`, wantErr: true},
+		{name: "Invalid regex", expr: `msg matches "("`, wantErr: true},
+		{name: "Non-literal pattern", expr: `msg matches pattern`, wantErr: true},
+		{name: "Unexpected character", expr: `level # "ERROR"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseWhere(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseWhere(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWhereMatch(t *testing.T) {
+	data := map[string]any{
+		"level":     "WARN",
+		"http_code": float64(503),
+		"uri":       "/api/v1/orders",
+		"msg":       "upstream timeout",
+		"debug":     false,
+		"retries":   "4",
+		"time":      "2024-03-20T10:00:00Z",
+		"trace_id":  json.Number("12345678901234567890"),
+		"http": map[string]any{
+			"method": "POST",
+		},
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		want bool
+	}{
+		{name: "String equality", expr: `level == "WARN"`, want: true},
+		{name: "Single equals", expr: `level = "ERROR"`, want: false},
+		{name: "Inequality", expr: `level != "ERROR"`, want: true},
+		{name: "Numeric comparison", expr: `http_code >= 500`, want: true},
+		{name: "Numeric string field", expr: `retries > 3`, want: true},
+		{name: "Integer beyond int64", expr: `trace_id == 12345678901234567891`, want: false},
+		{name: "Integer beyond int64 equal", expr: `trace_id == 12345678901234567890`, want: true},
+		{name: "Time comparison", expr: `time < "2024-03-20T10:00:01Z"`, want: true},
+		{name: "Or", expr: `level == "ERROR" || http_code >= 500`, want: true},
+		{name: "And", expr: `level == "ERROR" && http_code >= 500`, want: false},
+		{name: "Precedence", expr: `level == "ERROR" && http_code >= 500 || uri contains "orders"`, want: true},
+		{name: "Parentheses", expr: `level == "ERROR" && (http_code >= 500 || uri contains "orders")`, want: false},
+		{name: "Not", expr: `!(uri startsWith "/health")`, want: true},
+		{name: "Not keyword", expr: `not level == "WARN"`, want: false},
+		{name: "Contains", expr: `msg contains "timeout"`, want: true},
+		{name: "Ends with", expr: `uri endsWith "orders"`, want: true},
+		{name: "Matches", expr: `uri matches "^/api/v[0-9]+/"`, want: true},
+		{name: "Regex operator", expr: `msg =~ "refused"`, want: false},
+		{name: "Function form", expr: `startsWith(uri, "/api")`, want: true},
+		{name: "Exists", expr: `exists(uri)`, want: true},
+		{name: "Exists missing", expr: `exists(user)`, want: false},
+		{name: "Nested field", expr: `http.method == "POST"`, want: true},
+		{name: "Missing field comparison", expr: `user == "alice"`, want: false},
+		{name: "Missing field inequality", expr: `user != "alice"`, want: true},
+		{name: "Null comparison", expr: `user == null`, want: true},
+		{name: "Bare false field", expr: `debug`, want: false},
+		{name: "Bare missing field", expr: `user`, want: false},
+		{name: "Boolean literal", expr: `debug == false`, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, err := ParseWhere(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseWhere(%q) error = %v", tt.expr, err)
+			}
+			if got := where.match(newRecord(data, 2), nil, TimeSettings{}); got != tt.want {
+				t.Errorf("match(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWhereLevels(t *testing.T) {
+	levels, err := NewLevels(map[string]string{"5": "ERROR"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		data map[string]any
+		want bool
+	}{
+		{name: "Synonym in record", expr: `level == "WARN"`, data: map[string]any{"level": "warning"}, want: true},
+		{name: "Synonym in expression", expr: `level == "warning"`, data: map[string]any{"level": "WARN"}, want: true},
+		{name: "Bunyan number", expr: `level == "ERROR"`, data: map[string]any{"level": float64(50)}, want: true},
+		{name: "Custom mapping", expr: `level == "ERROR"`, data: map[string]any{"level": float64(5)}, want: true},
+		{name: "OpenTelemetry alias", expr: `severityNumber == "ERROR"`, data: map[string]any{"severityNumber": float64(17)}, want: true},
+		{name: "Not equal", expr: `level != "WARN"`, data: map[string]any{"level": "warning"}, want: false},
+		{name: "By severity", expr: `level >= "WARN"`, data: map[string]any{"level": "crit"}, want: true},
+		{name: "Below severity", expr: `level > "WARN"`, data: map[string]any{"level": float64(40)}, want: false},
+		{name: "Matches normalized name", expr: `level matches "^(WARN|ERROR)$"`, data: map[string]any{"level": "err"}, want: true},
+		{name: "Unknown level as written", expr: `level == "chatty"`, data: map[string]any{"level": "chatty"}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, err := ParseWhere(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseWhere(%q) error = %v", tt.expr, err)
+			}
+			if got := where.match(newRecord(tt.data, 2), levels, TimeSettings{}); got != tt.want {
+				t.Errorf("match(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWhereTimeSettings(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip("timezone data not available")
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		data map[string]any
+		ts   TimeSettings
+		want bool
+	}{
+		{name: "Input timezone", expr: `time >= "2024-03-20T05:00:00Z"`, data: map[string]any{"time": "2024-03-20 10:00:00"}, ts: TimeSettings{InputLocation: tokyo}, want: false},
+		{name: "UTC without input timezone", expr: `time >= "2024-03-20T05:00:00Z"`, data: map[string]any{"time": "2024-03-20 10:00:00"}, want: true},
+		{name: "Custom layout", expr: `time < "2024-11-28T22:00:00Z"`, data: map[string]any{"time": "28/Nov/2024:21:30:45 +0000"}, ts: TimeSettings{Layouts: NewTimeLayouts([]string{"02/Jan/2006:15:04:05 -0700"})}, want: true},
+		{name: "Epoch unit", expr: `ts > "2022-03-20T08:00:00Z"`, data: map[string]any{"ts": json.Number("1647763200123")}, ts: TimeSettings{EpochUnit: time.Microsecond}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, err := ParseWhere(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseWhere(%q) error = %v", tt.expr, err)
+			}
+			if got := where.match(newRecord(tt.data, 2), nil, tt.ts); got != tt.want {
+				t.Errorf("match(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
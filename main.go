@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	_ "time/tzdata" // timezone database for --tz on systems without one
 
 	"github.com/techarm/jclog/cmd"
 )
@@ -0,0 +1,84 @@
+#!/bin/sh
+# Compares BenchmarkProcessLog with the ProcessLog of a baseline release on
+# the same input. The baseline is checked out into a temporary worktree,
+# given a benchmark for its own ProcessLog, and both are run with -count.
+#
+# Usage: scripts/bench-baseline.sh [ref] [count]
+#   ref    baseline commit or tag (default: v0.3.6)
+#   count  runs of each benchmark (default: 5)
+#
+# Only the Basic and HideMissing formats are compared; the baseline has no
+# format specs, sections or {rest}. The output is fed to benchstat when it
+# is installed (go install golang.org/x/perf/cmd/benchstat@latest).
+set -e
+
+ref=${1:-v0.3.6}
+count=${2:-5}
+root=$(git rev-parse --show-toplevel)
+work=$(mktemp -d)
+trap 'git -C "$root" worktree remove --force "$work/baseline" >/dev/null 2>&1; rm -rf "$work"' EXIT
+
+git -C "$root" worktree add --detach "$work/baseline" "$ref" >/dev/null 2>&1
+
+# Use the very line the current benchmarks decode
+bench_line=$(grep '^const benchLine' "$root/internal/logparser/program_test.go")
+
+cat > "$work/baseline/internal/logparser/baseline_bench_test.go" <<EOF
+package logparser
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"testing"
+)
+
+$bench_line
+
+// Same formats as benchFormats in program_test.go
+var benchFormats = []struct {
+	name        string
+	format      string
+	hideMissing bool
+}{
+	{name: "Basic", format: "{time} [{level}] {message}"},
+	{name: "HideMissing", format: "{time} [{level}] {message} (req={request_id}) {user} {caller|basename}", hideMissing: true},
+}
+
+func BenchmarkProcessLog(b *testing.B) {
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer devNull.Close()
+	input := strings.Repeat(benchLine+"\n", 1000)
+
+	for _, bf := range benchFormats {
+		b.Run(bf.name, func(b *testing.B) {
+			stdout := os.Stdout
+			os.Stdout = devNull
+			defer func() { os.Stdout = stdout }()
+
+			b.SetBytes(int64(len(input)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				ProcessLog(bufio.NewScanner(strings.NewReader(input)), bf.format, 2, bf.hideMissing, nil, nil, nil, false, "2006-01-02 15:04:05")
+			}
+		})
+	}
+}
+EOF
+
+bench='^BenchmarkProcessLog/(Basic|HideMissing)$'
+(cd "$work/baseline" && go test -run '^$' -bench "$bench" -benchmem -count "$count" ./internal/logparser) > "$work/baseline.txt"
+(cd "$root" && go test -run '^$' -bench "$bench" -benchmem -count "$count" ./internal/logparser) > "$work/current.txt"
+
+if command -v benchstat >/dev/null 2>&1; then
+	benchstat "$work/baseline.txt" "$work/current.txt"
+else
+	echo "# baseline ($ref)"
+	grep '^Benchmark' "$work/baseline.txt"
+	echo "# current"
+	grep '^Benchmark' "$work/current.txt"
+fi